@@ -2,6 +2,7 @@ package component
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net"
 	"net/http"
@@ -17,6 +18,10 @@ import (
 	"github.com/sustglobal/gost/httpapi"
 )
 
+// metrics is published to expvar as "gost". Each new Component replaces
+// the entries of any previous one, since expvar names are process-global.
+var metrics = expvar.NewMap("gost")
+
 func NewFromEnv() (*Component, error) {
 	cfg := DefaultConfig()
 	if err := LoadFromEnv(&cfg); err != nil {
@@ -39,15 +44,45 @@ func New(cfg Config) (*Component, error) {
 		return nil, err
 	}
 
+	inboundErrorPolicy, err := event.ParseErrorPolicy(cfg.InboundErrorPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	cmp := Component{
 		Config: cfg,
 
 		Logger:              logger,
+		Health:              new(httpapi.HealthRegistry),
 		HTTPRouter:          mux.NewRouter(),
 		InboundEventRouter:  event.NewEventRouter(logger),
 		OutboundEventRouter: event.NewEventRouter(logger),
+
+		circuitBreakers: new(expvar.Map).Init(),
+	}
+
+	// a panicking handler fails its event rather than the component
+	cmp.InboundEventRouter.Use(event.Recover(logger))
+	cmp.OutboundEventRouter.Use(event.Recover(logger))
+
+	// failed inbound events may be reported to the transport, e.g. so
+	// that PubSub redelivers them
+	cmp.InboundEventRouter.ErrorPolicy = inboundErrorPolicy
+
+	// outbound events are attributed to this component unless they
+	// already name a source
+	cmp.OutboundEventRouter.Source = cfg.Name
+
+	if cfg.LogOutboundEvents {
+		cmp.OutboundEventRouter.Mount(event.NewLogHandler(logger, event.LogHandlerConfig{
+			Message: "outbound event",
+		}))
 	}
 
+	metrics.Set("inbound_event_router", cmp.InboundEventRouter.Metrics)
+	metrics.Set("outbound_event_router", cmp.OutboundEventRouter.Metrics)
+	metrics.Set("circuit_breakers", cmp.circuitBreakers)
+
 	cmp.httpServer = &http.Server{
 		Addr:    cfg.BindHTTPServer,
 		Handler: cmp.HTTPRouter,
@@ -57,7 +92,7 @@ func New(cfg Config) (*Component, error) {
 		httpapi.NewMetricsHandler().Mount(cmp.HTTPRouter)
 	}
 	if cfg.ExposeHealth {
-		httpapi.NewHealthHandler().Mount(cmp.HTTPRouter)
+		httpapi.NewHealthRegistryHandler(cmp.Health).Mount(cmp.HTTPRouter)
 	}
 
 	return &cmp, nil
@@ -71,11 +106,29 @@ type Component struct {
 	OutboundEventRouter *event.EventRouter
 	Logger              *zap.Logger
 
+	// Health holds the checks consulted by the health endpoint
+	Health *httpapi.HealthRegistry
+
+	circuitBreakers *expvar.Map
+
 	httpServer *http.Server
 	asyncDone  chan struct{}
 	asyncError error
 }
 
+// RegisterCircuitBreaker publishes the breaker state in the component
+// metrics and the health response. An open breaker only fails health
+// checks if FailHealthOnOpenBreaker is set: a dependency outage would
+// otherwise have every replica restarted or taken out of rotation.
+func (c *Component) RegisterCircuitBreaker(b *event.CircuitBreaker) {
+	c.circuitBreakers.Set(b.Name(), b.Var())
+	if c.Config.FailHealthOnOpenBreaker {
+		c.Health.Register(b)
+	} else {
+		c.Health.RegisterAdvisory(b)
+	}
+}
+
 func (c *Component) Start() error {
 	var network, addr string
 	if strings.HasPrefix(c.Config.BindHTTPServer, "unix://") {
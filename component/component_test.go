@@ -2,14 +2,20 @@ package component
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"syscall"
 	"testing"
 	"time"
+
+	"github.com/sustglobal/gost/event"
 )
 
 func newUnixDomainSocket(t *testing.T) (string, *http.Client) {
@@ -114,3 +120,113 @@ func TestComponentRun(t *testing.T) {
 		t.Errorf("Component.Error returned err=%v", err)
 	}
 }
+
+func TestComponentLogOutboundEvents(t *testing.T) {
+	cfg := DefaultConfig()
+
+	cmp, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cmp.OutboundEventRouter.Routes(); len(got) != 0 {
+		t.Errorf("expected no outbound handlers by default, got %v", got)
+	}
+
+	cfg.LogOutboundEvents = true
+
+	cmp, err = New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the log handler accepts events of every type
+	routes := cmp.OutboundEventRouter.Routes()
+	if len(routes) != 1 || len(routes[event.AnyEventType]) != 1 {
+		t.Errorf("expected a single untyped outbound log handler, got %v", routes)
+	}
+}
+
+func TestComponentEventRouterMetrics(t *testing.T) {
+	cmp, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmp.InboundEventRouter.HandleEvent(context.Background(), &event.Event{Type: event.EventType("test")})
+
+	published, ok := expvar.Get("gost").(*expvar.Map)
+	if !ok {
+		t.Fatalf("expected gost metrics to be published")
+	}
+	inbound, ok := published.Get("inbound_event_router").(*expvar.Map)
+	if !ok || inbound != cmp.InboundEventRouter.Metrics {
+		t.Fatalf("expected inbound router metrics to be published")
+	}
+	if got := inbound.Get("events").String(); got != "1" {
+		t.Errorf("unexpected events count: want=1 got=%s", got)
+	}
+}
+
+func TestComponentInboundErrorPolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InboundErrorPolicy = "fail-if-any"
+
+	cmp, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cmp.InboundEventRouter.ErrorPolicy; got != event.ErrorPolicyFailIfAny {
+		t.Errorf("unexpected inbound error policy: want=%v got=%v", event.ErrorPolicyFailIfAny, got)
+	}
+
+	cfg.InboundErrorPolicy = "sometimes"
+	if _, err := New(cfg); err == nil {
+		t.Errorf("expected error for unknown error policy")
+	}
+}
+
+func TestComponentRegisterCircuitBreaker(t *testing.T) {
+	for _, failHealth := range []bool{false, true} {
+		cfg := DefaultConfig()
+		cfg.ExposeHealth = true
+		cfg.FailHealthOnOpenBreaker = failHealth
+
+		cmp, err := New(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		name := fmt.Sprintf("publisher_%t", failHealth)
+		b := event.NewCircuitBreaker(event.BreakerConfig{Name: name, MinRequests: 1})
+		cmp.RegisterCircuitBreaker(b)
+
+		healthz := func() (int, string) {
+			rec := httptest.NewRecorder()
+			cmp.HTTPRouter.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+			var resp struct{ Checks map[string]string }
+			json.NewDecoder(rec.Body).Decode(&resp)
+			return rec.Code, resp.Checks[name]
+		}
+
+		if code, check := healthz(); code != 200 || check != "ok" {
+			t.Errorf("unexpected health: code=%d check=%q", code, check)
+		}
+
+		failing := event.WrapHandler(event.NewLogHandler(cmp.Logger, event.LogHandlerConfig{}), func(ctx context.Context, ev *event.Event) error {
+			return errors.New("unavailable")
+		})
+		b.Wrap(failing).HandleEvent(context.Background(), &event.Event{})
+
+		want := 200
+		if failHealth {
+			want = 503
+		}
+		if code, check := healthz(); code != want || check != event.ErrCircuitOpen.Error() {
+			t.Errorf("failHealth=%t: unexpected health: want=%d got code=%d check=%q", failHealth, want, code, check)
+		}
+
+		breakers := expvar.Get("gost").(*expvar.Map).Get("circuit_breakers").(*expvar.Map)
+		if breakers.Get(name) == nil {
+			t.Errorf("expected breaker to be published")
+		}
+	}
+}
@@ -10,17 +10,22 @@ import (
 )
 
 type Config struct {
+	Name                    string        `env:"GOST_COMPONENT_NAME"`
 	BindHTTPServer          string        `env:"GOST_BIND_HTTP_SERVER" default:"0.0.0.0:8080"`
 	ExposeMetrics           bool          `env:"GOST_EXPOSE_METRICS" default:"false"`
 	ExposeHealth            bool          `env:"GOST_EXPOSE_HEALTH" default:"false"`
 	GracefulShutdownTimeout time.Duration `env:"GOST_GRACEFUL_SHUTDOWN_TIMEOUT" default:"60s"`
 	Debug                   bool          `env:"GOST_DEBUG" default:"false"`
+	LogOutboundEvents       bool          `env:"GOST_LOG_OUTBOUND_EVENTS" default:"false"`
+	InboundErrorPolicy      string        `env:"GOST_INBOUND_ERROR_POLICY" default:"ignore"`
+	FailHealthOnOpenBreaker bool          `env:"GOST_FAIL_HEALTH_ON_OPEN_BREAKER" default:"false"`
 }
 
 func DefaultConfig() Config {
 	return Config{
 		BindHTTPServer:          "0.0.0.0:8080",
 		GracefulShutdownTimeout: 60 * time.Second,
+		InboundErrorPolicy:      "ignore",
 	}
 }
 
@@ -0,0 +1,329 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	stringsType  = reflect.TypeOf([]string{})
+)
+
+// DecodeError aggregates every field that could not be bound by Decode.
+type DecodeError struct {
+	Errors []error
+}
+
+func (e *DecodeError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("failed decoding event: %s", strings.Join(msgs, "; "))
+}
+
+// Is reports whether any of the aggregated errors matches target, so that
+// callers can continue to check for ErrFieldMissing and friends.
+func (e *DecodeError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type bindTag struct {
+	key       EventFieldKey
+	required  bool
+	omitEmpty bool
+}
+
+func parseBindTag(sf reflect.StructField) (bindTag, bool) {
+	tag := sf.Tag.Get("event")
+	if tag == "" || tag == "-" || sf.PkgPath != "" {
+		return bindTag{}, false
+	}
+
+	parts := strings.Split(tag, ",")
+	bt := bindTag{key: EventFieldKey(parts[0])}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "required":
+			bt.required = true
+		case "optional":
+			bt.required = false
+		case "omitempty":
+			bt.omitEmpty = true
+		}
+	}
+	return bt, bt.key != ""
+}
+
+// Decode binds the fields of ev onto the struct pointed to by dst. Struct
+// fields are mapped using `event:"key"` tags; untagged fields are ignored.
+// A key may be marked `event:"key,required"`, otherwise it is optional and
+// left untouched (or set from a `default:"..."` tag) when missing. All
+// missing and malformed keys are reported together in a *DecodeError.
+func Decode(ev *Event, dst interface{}) error {
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode destination must be a non-nil struct pointer, got %T", dst)
+	}
+
+	val = val.Elem()
+	typ := val.Type()
+
+	var errs []error
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		bt, ok := parseBindTag(sf)
+		if !ok {
+			continue
+		}
+
+		err := decodeField(ev.Detailed(), bt.key, val.Field(i))
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, ErrFieldMissing) {
+			if def, ok := sf.Tag.Lookup("default"); ok {
+				err = decodeDefault(def, val.Field(i))
+			} else if !bt.required {
+				err = nil
+			}
+		}
+
+		if err != nil {
+			var ferr *FieldError
+			if !errors.As(err, &ferr) {
+				ferr = newFieldError(bt.key, sf.Type.String(), nil, ErrFieldUnexpectedValue, err)
+				ferr.Got = ""
+			}
+			errs = append(errs, ferr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &DecodeError{Errors: errs}
+	}
+	return nil
+}
+
+func decodeField(ev DetailedEvent, key EventFieldKey, fv reflect.Value) error {
+	if fv.Kind() == reflect.Ptr {
+		val, err := ev.Field(key)
+		if err != nil {
+			return err
+		}
+		// a null value, as written by Encode for a nil pointer, leaves
+		// the pointer nil
+		if val == nil {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		pv := reflect.New(fv.Type().Elem())
+		if err := decodeField(ev, key, pv.Elem()); err != nil {
+			return err
+		}
+		fv.Set(pv)
+		return nil
+	}
+
+	switch fv.Type() {
+	case timeType:
+		tv, err := ev.TimeField(key)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(tv))
+		return nil
+	case durationType:
+		dv, err := ev.DurationField(key)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(dv))
+		return nil
+	case stringsType:
+		sv, err := ev.StringSliceField(key)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(sv))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		sv, err := ev.StringField(key)
+		if err != nil {
+			return err
+		}
+		fv.SetString(sv)
+	case reflect.Bool:
+		bv, err := ev.BoolField(key)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(bv)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		iv, err := ev.Int64Field(key)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(iv) {
+			return newFieldError(key, fv.Type().String(), iv, ErrFieldUnexpectedValue, nil)
+		}
+		fv.SetInt(iv)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uv, err := ev.Uint64Field(key)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowUint(uv) {
+			return newFieldError(key, fv.Type().String(), uv, ErrFieldUnexpectedValue, nil)
+		}
+		fv.SetUint(uv)
+	case reflect.Float32, reflect.Float64:
+		fl, err := ev.FloatField(key)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowFloat(fl) {
+			return newFieldError(key, fv.Type().String(), fl, ErrFieldUnexpectedValue, nil)
+		}
+		fv.SetFloat(fl)
+	default:
+		return ev.JSONField(key, fv.Addr().Interface())
+	}
+
+	return nil
+}
+
+func decodeDefault(def string, fv reflect.Value) error {
+	if fv.Kind() == reflect.Ptr {
+		pv := reflect.New(fv.Type().Elem())
+		if err := decodeDefault(def, pv.Elem()); err != nil {
+			return err
+		}
+		fv.Set(pv)
+		return nil
+	}
+
+	switch fv.Type() {
+	case timeType:
+		tmp := Event{Fields: []EventField{Field("default", def)}}
+		tv, err := tmp.TimeField("default")
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %v", def, err)
+		}
+		fv.Set(reflect.ValueOf(tv))
+		return nil
+	case durationType:
+		dv, err := time.ParseDuration(def)
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %v", def, err)
+		}
+		fv.SetInt(int64(dv))
+		return nil
+	}
+
+	var err error
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(def)
+	case reflect.Bool:
+		var bv bool
+		if bv, err = strconv.ParseBool(def); err == nil {
+			fv.SetBool(bv)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var iv int64
+		if iv, err = strconv.ParseInt(def, 10, fv.Type().Bits()); err == nil {
+			fv.SetInt(iv)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var uv uint64
+		if uv, err = strconv.ParseUint(def, 10, fv.Type().Bits()); err == nil {
+			fv.SetUint(uv)
+		}
+	case reflect.Float32, reflect.Float64:
+		var fl float64
+		if fl, err = strconv.ParseFloat(def, fv.Type().Bits()); err == nil {
+			fv.SetFloat(fl)
+		}
+	default:
+		err = json.Unmarshal([]byte(def), fv.Addr().Interface())
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid default %q: %v", def, err)
+	}
+	return nil
+}
+
+// Encode builds a new event of the given type from a struct tagged in the
+// same manner as expected by Decode.
+func Encode(typ EventType, src interface{}) (*Event, error) {
+	fields, err := EncodeFields(src)
+	if err != nil {
+		return nil, err
+	}
+	return NewEvent(typ, fields...), nil
+}
+
+// EncodeFields converts a tagged struct into event fields. Keys marked
+// `event:"key,omitempty"` are skipped when holding a zero value. Times and
+// durations are encoded as strings so they survive a JSON round trip.
+func EncodeFields(src interface{}) ([]EventField, error) {
+	val := reflect.Indirect(reflect.ValueOf(src))
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("encode source must be a struct, got %T", src)
+	}
+
+	typ := val.Type()
+
+	fields := make([]EventField, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		bt, ok := parseBindTag(typ.Field(i))
+		if !ok {
+			continue
+		}
+
+		fv := val.Field(i)
+		if bt.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				fields = append(fields, Field(bt.key, nil))
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		var v interface{}
+		switch fv.Type() {
+		case timeType:
+			v = fv.Interface().(time.Time).Format(time.RFC3339Nano)
+		case durationType:
+			v = time.Duration(fv.Int()).String()
+		default:
+			v = fv.Interface()
+		}
+
+		fields = append(fields, Field(bt.key, v))
+	}
+
+	return fields, nil
+}
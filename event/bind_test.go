@@ -0,0 +1,183 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindFixture struct {
+	Name     string        `event:"name,required"`
+	Count    int           `event:"count"`
+	Ratio    float32       `event:"ratio" default:"0.5"`
+	Enabled  bool          `event:"enabled"`
+	At       time.Time     `event:"at"`
+	Every    time.Duration `event:"every" default:"5s"`
+	Tags     []string      `event:"tags,omitempty"`
+	Detail   *jsonMessage  `event:"detail,omitempty"`
+	Region   *string       `event:"region,omitempty"`
+	Ignored  string
+	internal string `event:"internal"`
+}
+
+func TestDecode(t *testing.T) {
+	raw := `{
+  "type": "example_type",
+  "fields": [
+    {"key": "name", "value": "XYZ"},
+    {"key": "count", "value": 3},
+    {"key": "enabled", "value": true},
+    {"key": "at", "value": "2022-03-04T01:13:44Z"},
+    {"key": "tags", "value": ["a", "b"]},
+    {"key": "detail", "value": {"foo": "ABC", "bar": 1.5}}
+  ]
+}`
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	var got bindFixture
+	if err := Decode(&ev, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := bindFixture{
+		Name:    "XYZ",
+		Count:   3,
+		Ratio:   0.5,
+		Enabled: true,
+		At:      time.Date(2022, time.March, 4, 1, 13, 44, 0, time.UTC),
+		Every:   5 * time.Second,
+		Tags:    []string{"a", "b"},
+		Detail:  &jsonMessage{Foo: "ABC", Bar: 1.5},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected output: want=%+v got=%+v", want, got)
+	}
+}
+
+func TestDecodeAggregatesErrors(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("count", "three"),
+		Field("enabled", 1),
+	)
+
+	var got bindFixture
+	err := Decode(ev, &got)
+
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected *DecodeError, got %v", err)
+	}
+	if len(derr.Errors) != 3 {
+		t.Errorf("expected 3 errors, got %d: %v", len(derr.Errors), derr)
+	}
+
+	for _, key := range []string{"name", "count", "enabled"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error message does not name key %q: %v", key, err)
+		}
+	}
+
+	if !errors.Is(err, ErrFieldMissing) {
+		t.Errorf("expected error to match ErrFieldMissing")
+	}
+	if !errors.Is(err, ErrFieldIncorrectType) {
+		t.Errorf("expected error to match ErrFieldIncorrectType")
+	}
+}
+
+func TestDecodeInvalidDestination(t *testing.T) {
+	ev := NewEvent(EventType("example_type"))
+
+	if err := Decode(ev, bindFixture{}); err == nil {
+		t.Errorf("expected error decoding into non-pointer")
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	region := "EU"
+	want := bindFixture{
+		Name:   "XYZ",
+		Count:  3,
+		Ratio:  0.25,
+		At:     time.Date(2022, time.March, 4, 1, 13, 44, 500, time.UTC),
+		Every:  time.Minute,
+		Detail: &jsonMessage{Foo: "ABC"},
+		Region: &region,
+	}
+
+	ev, err := Encode(EventType("example_type"), &want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ev.Type != EventType("example_type") {
+		t.Errorf("unexpected event type: %v", ev.Type)
+	}
+	if _, err := ev.Field(EventFieldKey("tags")); err != ErrFieldMissing {
+		t.Errorf("expected omitempty field to be skipped, got err=%v", err)
+	}
+
+	bev, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("failed marshaling JSON: %v", err)
+	}
+
+	var dev Event
+	if err := json.Unmarshal(bev, &dev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	var got bindFixture
+	if err := Decode(&dev, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected output: want=%+v got=%+v", want, got)
+	}
+}
+
+func TestEncodeDecodeNilPointer(t *testing.T) {
+	type fixture struct {
+		Region *string `event:"region"`
+		N      *int    `event:"n"`
+	}
+
+	ev, err := Encode(EventType("example_type"), &fixture{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got fixture
+	if err := Decode(ev, &got); err != nil {
+		t.Fatalf("unexpected error decoding in-process event: %v", err)
+	}
+	if got.Region != nil || got.N != nil {
+		t.Errorf("expected nil pointers, got %+v", got)
+	}
+
+	bev, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("failed marshaling JSON: %v", err)
+	}
+	var dev Event
+	if err := json.Unmarshal(bev, &dev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	region := "EU"
+	got = fixture{Region: &region}
+	if err := Decode(&dev, &got); err != nil {
+		t.Fatalf("unexpected error decoding JSON event: %v", err)
+	}
+	if got.Region != nil || got.N != nil {
+		t.Errorf("expected nil pointers, got %+v", got)
+	}
+}
@@ -0,0 +1,302 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned in place of calling a handler whose circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type BreakerState int
+
+const (
+	// BreakerClosed passes every event to the handler
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every event with ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen passes a limited number of trial events to the
+	// handler to decide whether to close or re-open
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+type BreakerConfig struct {
+	// Name identifies the breaker in logs, metrics and health checks,
+	// defaulting to the name of the first handler it wraps
+	Name string
+
+	// FailureRatio of calls within Window that opens the breaker,
+	// defaulting to 0.5, once at least MinRequests (default 10) calls
+	// have completed within the Window (default 1m)
+	FailureRatio float64
+	MinRequests  int
+	Window       time.Duration
+
+	// Cooldown is how long the breaker stays open before allowing trial
+	// calls, defaulting to 30s
+	Cooldown time.Duration
+	// HalfOpenRequests is the number of trial calls that must succeed to
+	// close the breaker again, defaulting to 1
+	HalfOpenRequests int
+
+	// IsFailure classifies handler errors. By default every failure
+	// counts except cancellation by the caller.
+	IsFailure func(error) bool
+
+	Logger *zap.Logger
+}
+
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	epoch     int64
+	successes int
+	failures  int
+}
+
+// CircuitBreaker stops calling a failing handler for a cool-down period,
+// so that callers fail fast with ErrCircuitOpen rather than blocking on a
+// degraded downstream such as a publisher. A CircuitBreaker guards a
+// single downstream, and may wrap one or more handlers calling it.
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	openedAt time.Time
+	// generation changes with every state change, so that calls started
+	// in a previous state do not affect the current one
+	generation int
+	trials     int
+	trialOKs   int
+	buckets    [breakerBuckets]breakerBucket
+	rejected   int64
+
+	now func() time.Time
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool {
+			return isHandlerFailure(err) && !errors.Is(err, context.Canceled)
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	return &CircuitBreaker{cfg: cfg, now: time.Now}
+}
+
+// CircuitBreak wraps handlers with the given breaker.
+func CircuitBreak(b *CircuitBreaker) Middleware {
+	return b.Wrap
+}
+
+// Wrap returns next guarded by the breaker.
+func (b *CircuitBreaker) Wrap(next EventHandler) EventHandler {
+	b.mu.Lock()
+	if b.cfg.Name == "" {
+		b.cfg.Name = handlerName(next)
+	}
+	b.mu.Unlock()
+
+	return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+		gen, err := b.allow()
+		if err != nil {
+			return err
+		}
+		err = next.HandleEvent(ctx, ev)
+		b.record(gen, err)
+		return err
+	})
+}
+
+func (b *CircuitBreaker) Name() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cfg.Name
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.now())
+	return b.state
+}
+
+// CheckHealth reports ErrCircuitOpen while the breaker is open, so that a
+// breaker may be used as a health check.
+func (b *CircuitBreaker) CheckHealth(ctx context.Context) error {
+	if b.State() == BreakerOpen {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Var exposes the breaker state and counts within the current window for
+// publishing via expvar.
+func (b *CircuitBreaker) Var() expvar.Var {
+	return expvar.Func(func() interface{} {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		now := b.now()
+		b.advance(now)
+		successes, failures := b.counts(now)
+		return map[string]interface{}{
+			"state":     b.state.String(),
+			"successes": successes,
+			"failures":  failures,
+			"rejected":  b.rejected,
+		}
+	})
+}
+
+// allow reports whether a call may proceed, returning the generation to
+// record its outcome against.
+func (b *CircuitBreaker) allow() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+
+	switch b.state {
+	case BreakerOpen:
+		b.rejected++
+		return 0, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.trials >= b.cfg.HalfOpenRequests {
+			b.rejected++
+			return 0, ErrCircuitOpen
+		}
+		b.trials++
+	}
+	return b.generation, nil
+}
+
+func (b *CircuitBreaker) record(gen int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen != b.generation {
+		return
+	}
+
+	now := b.now()
+	failed := b.cfg.IsFailure(err)
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.setState(BreakerOpen, now)
+			return
+		}
+		b.trialOKs++
+		if b.trialOKs >= b.cfg.HalfOpenRequests {
+			b.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		bkt := b.bucket(now)
+		if failed {
+			bkt.failures++
+		} else {
+			bkt.successes++
+		}
+
+		successes, failures := b.counts(now)
+		total := successes + failures
+		if failed && total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.FailureRatio {
+			b.setState(BreakerOpen, now)
+		}
+	}
+}
+
+// advance moves an open breaker to half-open once its cool-down elapses.
+func (b *CircuitBreaker) advance(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cfg.Cooldown {
+		b.setState(BreakerHalfOpen, now)
+	}
+}
+
+func (b *CircuitBreaker) setState(state BreakerState, now time.Time) {
+	b.cfg.Logger.Warn("circuit breaker state changed",
+		zap.String("breaker", b.cfg.Name),
+		zap.Stringer("from", b.state),
+		zap.Stringer("to", state),
+	)
+
+	b.state = state
+	b.generation++
+	b.trials = 0
+	b.trialOKs = 0
+
+	switch state {
+	case BreakerOpen:
+		b.openedAt = now
+	case BreakerClosed:
+		b.buckets = [breakerBuckets]breakerBucket{}
+	}
+}
+
+func (b *CircuitBreaker) bucketWidth() int64 {
+	w := int64(b.cfg.Window) / breakerBuckets
+	if w <= 0 {
+		w = 1
+	}
+	return w
+}
+
+func (b *CircuitBreaker) bucket(now time.Time) *breakerBucket {
+	epoch := now.UnixNano() / b.bucketWidth()
+	bkt := &b.buckets[epoch%breakerBuckets]
+	if bkt.epoch != epoch {
+		*bkt = breakerBucket{epoch: epoch}
+	}
+	return bkt
+}
+
+// counts sums outcomes within the sliding window ending at now.
+func (b *CircuitBreaker) counts(now time.Time) (successes, failures int) {
+	epoch := now.UnixNano() / b.bucketWidth()
+	for _, bkt := range b.buckets {
+		if epoch-bkt.epoch < breakerBuckets {
+			successes += bkt.successes
+			failures += bkt.failures
+		}
+	}
+	return successes, failures
+}
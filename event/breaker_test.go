@@ -0,0 +1,192 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type switchHandler struct {
+	err   error
+	calls int
+}
+
+func (h *switchHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	h.calls++
+	return h.err
+}
+
+func (h *switchHandler) Handles() []EventType {
+	return nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	now := time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	b := NewCircuitBreaker(BreakerConfig{
+		MinRequests: 4,
+		Cooldown:    time.Minute,
+		Logger:      zap.New(core),
+	})
+	b.now = func() time.Time { return now }
+
+	eh := &switchHandler{}
+	cb := b.Wrap(eh)
+	call := func() error {
+		return cb.HandleEvent(context.Background(), &Event{})
+	}
+
+	if b.Name() != "*event.switchHandler" {
+		t.Errorf("unexpected breaker name: %v", b.Name())
+	}
+
+	// 1 success then 2 failures is below MinRequests
+	call()
+	eh.err = errors.New("unavailable")
+	call()
+	call()
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected breaker to stay closed below MinRequests")
+	}
+
+	// 3 of 4 failed
+	call()
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected breaker to open, got %v", b.State())
+	}
+	if err := b.CheckHealth(context.Background()); err != ErrCircuitOpen {
+		t.Errorf("expected unhealthy breaker, got %v", err)
+	}
+
+	calls := eh.calls
+	if err := call(); err != ErrCircuitOpen || eh.calls != calls {
+		t.Errorf("expected open breaker to reject call, got err=%v", err)
+	}
+
+	// a failed trial re-opens
+	now = now.Add(time.Minute)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected breaker to be half-open after cooldown, got %v", b.State())
+	}
+	call()
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected failed trial to re-open breaker, got %v", b.State())
+	}
+
+	// a successful trial closes
+	now = now.Add(time.Minute)
+	eh.err = nil
+	if err := call(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected successful trial to close breaker, got %v", b.State())
+	}
+
+	var transitions []string
+	for _, entry := range logs.FilterMessage("circuit breaker state changed").All() {
+		fields := entry.ContextMap()
+		transitions = append(transitions, fields["from"].(string)+">"+fields["to"].(string))
+	}
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if !reflect.DeepEqual(want, transitions) {
+		t.Errorf("unexpected transitions: want=%v got=%v", want, transitions)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	now := time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	b := NewCircuitBreaker(BreakerConfig{MinRequests: 2, Window: 10 * time.Second})
+	b.now = func() time.Time { return now }
+
+	cb := b.Wrap(&switchHandler{err: errors.New("unavailable")})
+	cb.HandleEvent(context.Background(), &Event{})
+
+	// the first failure has left the window by the second
+	now = now.Add(11 * time.Second)
+	cb.HandleEvent(context.Background(), &Event{})
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected failures outside the window to be ignored")
+	}
+
+	now = now.Add(time.Second)
+	cb.HandleEvent(context.Background(), &Event{})
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected breaker to open, got %v", b.State())
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimit(t *testing.T) {
+	now := time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	b := NewCircuitBreaker(BreakerConfig{MinRequests: 1, Cooldown: time.Second, HalfOpenRequests: 2})
+	b.now = func() time.Time { return now }
+
+	eh := &switchHandler{err: errors.New("unavailable")}
+	cb := b.Wrap(eh)
+	cb.HandleEvent(context.Background(), &Event{})
+
+	now = now.Add(time.Second)
+	eh.err = nil
+
+	// simulate concurrent trials by deferring their results
+	gen1, err1 := b.allow()
+	gen2, err2 := b.allow()
+	_, err3 := b.allow()
+	if err1 != nil || err2 != nil || err3 != ErrCircuitOpen {
+		t.Fatalf("expected 2 trials to be allowed: %v %v %v", err1, err2, err3)
+	}
+
+	b.record(gen1, nil)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected breaker to await second trial, got %v", b.State())
+	}
+	b.record(gen2, nil)
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected breaker to close, got %v", b.State())
+	}
+
+	// results from a previous state are ignored
+	b.record(gen1, errors.New("late failure"))
+	if b.State() != BreakerClosed {
+		t.Errorf("expected stale result to be ignored, got %v", b.State())
+	}
+}
+
+func TestCircuitBreakerIgnoresCancellation(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{MinRequests: 1})
+	cb := b.Wrap(&switchHandler{err: context.Canceled})
+
+	cb.HandleEvent(context.Background(), &Event{})
+	if b.State() != BreakerClosed {
+		t.Errorf("expected cancellation not to count as a failure")
+	}
+}
+
+func TestCircuitBreakerVar(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{MinRequests: 1, Name: "publisher"})
+	cb := b.Wrap(&switchHandler{err: errors.New("unavailable")})
+
+	cb.HandleEvent(context.Background(), &Event{})
+	cb.HandleEvent(context.Background(), &Event{})
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(b.Var().String()), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]interface{}{"state": "open", "successes": 0.0, "failures": 1.0, "rejected": 1.0}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected var: want=%v got=%v", want, got)
+	}
+	if b.Name() != "publisher" {
+		t.Errorf("expected configured name to be kept, got %v", b.Name())
+	}
+}
@@ -0,0 +1,479 @@
+package event
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// CBORCodec encodes events as CBOR (RFC 8949), a compact binary
+// equivalent of the native JSON encoding. The event is structured exactly
+// as in JSON (a map with "type", "fields" and so on), but integers keep
+// their full 64-bit precision and []byte values are carried as raw byte
+// strings rather than base64.
+type CBORCodec struct {
+	// DuplicateKeys is applied to every decoded event
+	DuplicateKeys DuplicateKeyPolicy
+}
+
+func (c *CBORCodec) ContentType() string { return "application/cbor" }
+
+func (c *CBORCodec) Marshal(ev *Event) ([]byte, error) {
+	fields := make([]interface{}, len(ev.Fields))
+	for i, ef := range ev.Fields {
+		fields[i] = map[string]interface{}{
+			"key":   string(ef.Key),
+			"value": ef.Value,
+		}
+	}
+
+	obj := map[string]interface{}{
+		"type":   string(ev.Type),
+		"fields": fields,
+	}
+
+	set := func(k, v string) {
+		if v != "" {
+			obj[k] = v
+		}
+	}
+	set("id", ev.ID)
+	set("source", ev.Source)
+	set("correlation_id", ev.CorrelationID)
+	set("causation_id", ev.CausationID)
+	set("schema_version", ev.SchemaVersion)
+	if !ev.Time.IsZero() {
+		obj["time"] = ev.Time.Format(time.RFC3339Nano)
+	}
+
+	enc := cborEncoder{}
+	if err := enc.encode(obj); err != nil {
+		return nil, err
+	}
+	return enc.buf, nil
+}
+
+func (c *CBORCodec) Unmarshal(data []byte, ev *Event) error {
+	dec := cborDecoder{buf: data}
+	val, err := dec.decode(0)
+	if err != nil {
+		return err
+	}
+	if dec.off != len(data) {
+		return errors.New("cbor: trailing data after event")
+	}
+
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return errors.New("cbor: event must be a map")
+	}
+
+	str := func(k string) (string, error) {
+		v, ok := obj[k]
+		if !ok {
+			return "", nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("cbor: event %s must be a string", k)
+		}
+		return s, nil
+	}
+
+	var out Event
+	for k, dst := range map[string]*string{
+		"id":             &out.ID,
+		"source":         &out.Source,
+		"correlation_id": &out.CorrelationID,
+		"causation_id":   &out.CausationID,
+		"schema_version": &out.SchemaVersion,
+	} {
+		if *dst, err = str(k); err != nil {
+			return err
+		}
+	}
+
+	typ, err := str("type")
+	if err != nil {
+		return err
+	}
+	out.Type = EventType(typ)
+
+	ts, err := str("time")
+	if err != nil {
+		return err
+	}
+	if ts != "" {
+		if out.Time, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			return fmt.Errorf("cbor: bad event time: %v", err)
+		}
+	}
+
+	if raw, ok := obj["fields"]; ok && raw != nil {
+		items, ok := raw.([]interface{})
+		if !ok {
+			return errors.New("cbor: event fields must be an array")
+		}
+		out.Fields = make([]EventField, len(items))
+		for i, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return errors.New("cbor: event field must be a map")
+			}
+			key, ok := m["key"].(string)
+			if !ok {
+				return errors.New("cbor: event field key must be a string")
+			}
+			out.Fields[i] = Field(EventFieldKey(key), m["value"])
+		}
+	}
+
+	out.resetIndex()
+	*ev = out
+	return ev.Dedupe(c.DuplicateKeys)
+}
+
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+
+	cborMaxDepth = 256
+)
+
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, major<<5|25)
+		e.buf = appendUint(e.buf, n, 2)
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, major<<5|26)
+		e.buf = appendUint(e.buf, n, 4)
+	default:
+		e.buf = append(e.buf, major<<5|27)
+		e.buf = appendUint(e.buf, n, 8)
+	}
+}
+
+func (e *cborEncoder) int(v int64) {
+	if v < 0 {
+		e.head(cborNegInt, uint64(-1-v))
+	} else {
+		e.head(cborUint, uint64(v))
+	}
+}
+
+func (e *cborEncoder) float(v float64) {
+	e.buf = append(e.buf, cborSimple<<5|27)
+	e.buf = appendUint(e.buf, math.Float64bits(v), 8)
+}
+
+// appendUint appends the low size bytes of n in big-endian order
+func appendUint(buf []byte, n uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		buf = append(buf, byte(n>>(8*uint(i))))
+	}
+	return buf
+}
+
+func (e *cborEncoder) encode(val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xf6)
+	case bool:
+		if v {
+			e.buf = append(e.buf, 0xf5)
+		} else {
+			e.buf = append(e.buf, 0xf4)
+		}
+	case string:
+		e.head(cborText, uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	case []byte:
+		e.head(cborBytes, uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	case int:
+		e.int(int64(v))
+	case int8:
+		e.int(int64(v))
+	case int16:
+		e.int(int64(v))
+	case int32:
+		e.int(int64(v))
+	case int64:
+		e.int(v)
+	case uint:
+		e.head(cborUint, uint64(v))
+	case uint8:
+		e.head(cborUint, uint64(v))
+	case uint16:
+		e.head(cborUint, uint64(v))
+	case uint32:
+		e.head(cborUint, uint64(v))
+	case uint64:
+		e.head(cborUint, v)
+	case float32:
+		e.float(float64(v))
+	case float64:
+		e.float(v)
+	case json.Number:
+		if iv, err := v.Int64(); err == nil {
+			e.int(iv)
+		} else if fv, err := v.Float64(); err == nil {
+			e.float(fv)
+		} else {
+			return fmt.Errorf("cbor: bad number %q", v)
+		}
+	case time.Time:
+		return e.encode(v.Format(time.RFC3339Nano))
+	case []interface{}:
+		e.head(cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		e.head(cborMap, uint64(len(v)))
+		for k, item := range v {
+			e.head(cborText, uint64(len(k)))
+			e.buf = append(e.buf, k...)
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case json.RawMessage:
+		var generic interface{}
+		if err := json.Unmarshal(v, &generic); err != nil {
+			return err
+		}
+		return e.encode(generic)
+	default:
+		return e.encodeReflect(val)
+	}
+	return nil
+}
+
+// encodeReflect handles typed slices and maps directly and falls back to a
+// JSON round trip for anything else (e.g. structs), so that values take
+// the same shape they would in the JSON encoding.
+func (e *cborEncoder) encodeReflect(val interface{}) error {
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return e.encode(nil)
+		}
+		e.head(cborArray, uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.encode(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			if rv.IsNil() {
+				return e.encode(nil)
+			}
+			e.head(cborMap, uint64(rv.Len()))
+			iter := rv.MapRange()
+			for iter.Next() {
+				k := iter.Key().String()
+				e.head(cborText, uint64(len(k)))
+				e.buf = append(e.buf, k...)
+				if err := e.encode(iter.Value().Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return e.encode(nil)
+		}
+	}
+
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("cbor: cannot encode %T: %v", val, err)
+	}
+	return e.encode(json.RawMessage(raw))
+}
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+type cborDecoder struct {
+	buf []byte
+	off int
+}
+
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.off) {
+		return nil, errCBORTruncated
+	}
+	b := d.buf[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func (d *cborDecoder) head() (major byte, info byte, n uint64, err error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		b, err = d.next(1)
+		if err == nil {
+			n = uint64(b[0])
+		}
+	case info == 25:
+		b, err = d.next(2)
+		if err == nil {
+			n = uint64(binary.BigEndian.Uint16(b))
+		}
+	case info == 26:
+		b, err = d.next(4)
+		if err == nil {
+			n = uint64(binary.BigEndian.Uint32(b))
+		}
+	case info == 27:
+		b, err = d.next(8)
+		if err == nil {
+			n = binary.BigEndian.Uint64(b)
+		}
+	default:
+		err = fmt.Errorf("cbor: unsupported additional info %d", info)
+	}
+
+	return major, info, n, err
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.New("cbor: maximum nesting depth exceeded")
+	}
+
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case cborText:
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case cborArray:
+		// every item occupies at least one byte
+		if n > uint64(len(d.buf)-d.off) {
+			return nil, errCBORTruncated
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if n > uint64(len(d.buf)-d.off)/2 {
+			return nil, errCBORTruncated
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			ks, ok := k.(string)
+			if !ok {
+				return nil, errors.New("cbor: map keys must be text strings")
+			}
+			if m[ks], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case cborTag:
+		// tags carry no meaning for events, so decode the tagged value as-is
+		return d.decode(depth + 1)
+	}
+
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return halfToFloat64(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	}
+
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+}
+
+func halfToFloat64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1.0
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
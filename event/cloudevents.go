@@ -0,0 +1,284 @@
+package event
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Support for the CloudEvents 1.0 specification (https://cloudevents.io).
+// Event fields are carried as a JSON object in the CloudEvents data
+// attribute, and envelope metadata maps onto the corresponding context
+// attributes. Metadata without a CloudEvents equivalent is carried in
+// extension attributes.
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	CloudEventsContentType = "application/cloudevents+json"
+
+	cloudEventsDataContentType = "application/json"
+	cloudEventsDefaultSource   = "/"
+
+	// non-object data received from foreign producers is exposed under
+	// this field key
+	CloudEventsDataKey = EventFieldKey("data")
+)
+
+var ErrCloudEventInvalid = errors.New("invalid CloudEvent")
+
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+
+	CorrelationID string `json:"correlationid,omitempty"`
+	CausationID   string `json:"causationid,omitempty"`
+	SchemaVersion string `json:"schemaversion,omitempty"`
+}
+
+// MarshalCloudEvent encodes ev in CloudEvents structured JSON mode.
+func MarshalCloudEvent(ev *Event) ([]byte, error) {
+	attrs, data, err := MarshalCloudEventBinary(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	ce := cloudEvent{
+		SpecVersion:     attrs["specversion"],
+		ID:              attrs["id"],
+		Source:          attrs["source"],
+		Type:            attrs["type"],
+		Time:            attrs["time"],
+		DataContentType: attrs["datacontenttype"],
+		Data:            data,
+		CorrelationID:   attrs["correlationid"],
+		CausationID:     attrs["causationid"],
+		SchemaVersion:   attrs["schemaversion"],
+	}
+
+	return json.Marshal(&ce)
+}
+
+// UnmarshalCloudEvent decodes a CloudEvents structured JSON mode payload.
+func UnmarshalCloudEvent(data []byte, ev *Event) error {
+	var ce cloudEvent
+	if err := json.Unmarshal(data, &ce); err != nil {
+		return fmt.Errorf("%w: %v", ErrCloudEventInvalid, err)
+	}
+
+	attrs := map[string]string{
+		"specversion":     ce.SpecVersion,
+		"id":              ce.ID,
+		"source":          ce.Source,
+		"type":            ce.Type,
+		"time":            ce.Time,
+		"datacontenttype": ce.DataContentType,
+		"correlationid":   ce.CorrelationID,
+		"causationid":     ce.CausationID,
+		"schemaversion":   ce.SchemaVersion,
+	}
+
+	payload := []byte(ce.Data)
+	if ce.DataBase64 != "" {
+		raw, err := base64.StdEncoding.DecodeString(ce.DataBase64)
+		if err != nil {
+			return fmt.Errorf("%w: bad data_base64: %v", ErrCloudEventInvalid, err)
+		}
+		if err := unmarshalCloudEventAttributes(attrs, ev); err != nil {
+			return err
+		}
+		ev.Fields = []EventField{Field(CloudEventsDataKey, raw)}
+		ev.resetIndex()
+		return nil
+	}
+
+	// in structured mode, non-JSON data such as text/plain is carried as a
+	// JSON string and must be unquoted before being handed on as-is
+	if ct := ce.DataContentType; ct != "" && !isJSONContentType(ct) {
+		var str string
+		if err := json.Unmarshal(payload, &str); err == nil {
+			payload = []byte(str)
+		}
+	}
+
+	return UnmarshalCloudEventBinary(attrs, payload, ev)
+}
+
+// MarshalCloudEventBinary encodes ev for CloudEvents binary content mode,
+// returning the context attributes (keyed by bare attribute name, without
+// any transport-specific prefix) alongside the data payload.
+func MarshalCloudEventBinary(ev *Event) (map[string]string, []byte, error) {
+	attrs := map[string]string{
+		"specversion":     CloudEventsSpecVersion,
+		"id":              ev.ID,
+		"source":          ev.Source,
+		"type":            string(ev.Type),
+		"datacontenttype": cloudEventsDataContentType,
+	}
+
+	if attrs["id"] == "" {
+		attrs["id"] = NewEventID()
+	}
+	if attrs["source"] == "" {
+		attrs["source"] = cloudEventsDefaultSource
+	}
+	if !ev.Time.IsZero() {
+		attrs["time"] = ev.Time.Format(time.RFC3339Nano)
+	}
+	if ev.CorrelationID != "" {
+		attrs["correlationid"] = ev.CorrelationID
+	}
+	if ev.CausationID != "" {
+		attrs["causationid"] = ev.CausationID
+	}
+	if ev.SchemaVersion != "" {
+		attrs["schemaversion"] = ev.SchemaVersion
+	}
+
+	data, err := marshalFieldsObject(ev.Fields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attrs, data, nil
+}
+
+// UnmarshalCloudEventBinary decodes a CloudEvent received in binary
+// content mode. Attribute names must already be stripped of any transport
+// prefix (e.g. "ce-" for HTTP headers).
+func UnmarshalCloudEventBinary(attrs map[string]string, data []byte, ev *Event) error {
+	if err := unmarshalCloudEventAttributes(attrs, ev); err != nil {
+		return err
+	}
+
+	ev.Fields = nil
+	ev.resetIndex()
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	ct := attrs["datacontenttype"]
+	if ct != "" && !isJSONContentType(ct) {
+		ev.Fields = []EventField{Field(CloudEventsDataKey, string(data))}
+		return nil
+	}
+
+	fields, err := unmarshalFieldsObject(data)
+	if err != nil {
+		return fmt.Errorf("%w: bad data: %v", ErrCloudEventInvalid, err)
+	}
+	ev.Fields = fields
+
+	return nil
+}
+
+func unmarshalCloudEventAttributes(attrs map[string]string, ev *Event) error {
+	if !strings.HasPrefix(attrs["specversion"], "1.") {
+		return fmt.Errorf("%w: unsupported specversion %q", ErrCloudEventInvalid, attrs["specversion"])
+	}
+	for _, req := range []string{"id", "source", "type"} {
+		if attrs[req] == "" {
+			return fmt.Errorf("%w: missing required attribute %q", ErrCloudEventInvalid, req)
+		}
+	}
+
+	ev.ID = attrs["id"]
+	ev.Type = EventType(attrs["type"])
+	ev.Source = attrs["source"]
+	ev.CorrelationID = attrs["correlationid"]
+	ev.CausationID = attrs["causationid"]
+	ev.SchemaVersion = attrs["schemaversion"]
+	ev.Time = time.Time{}
+
+	if ts := attrs["time"]; ts != "" {
+		tv, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return fmt.Errorf("%w: bad time: %v", ErrCloudEventInvalid, err)
+		}
+		ev.Time = tv
+	}
+
+	return nil
+}
+
+func isJSONContentType(ct string) bool {
+	ct = strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	return ct == "application/json" || ct == "text/json" || strings.HasSuffix(ct, "+json")
+}
+
+// marshalFieldsObject encodes fields as a single JSON object, preserving
+// field order.
+func marshalFieldsObject(fields []EventField) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, ef := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(string(ef.Key))
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(ef.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed marshaling field %s: %v", ef.Key, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// unmarshalFieldsObject is the inverse of marshalFieldsObject. Data that is
+// valid JSON but not an object is exposed as a single field.
+func unmarshalFieldsObject(data []byte) ([]EventField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		var val interface{}
+		if err := json.Unmarshal(data, &val); err != nil {
+			return nil, err
+		}
+		return []EventField{Field(CloudEventsDataKey, val)}, nil
+	}
+
+	fields := make([]EventField, 0)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %v", tok)
+		}
+
+		var val interface{}
+		if err := dec.Decode(&val); err != nil {
+			return nil, err
+		}
+		fields = append(fields, Field(EventFieldKey(key), val))
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
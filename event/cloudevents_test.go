@@ -0,0 +1,136 @@
+package event
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func cloudEventFixture() *Event {
+	return &Event{
+		ID:            "abc-123",
+		Type:          EventType("dataset.ingest.completed"),
+		Source:        "/ingest",
+		Time:          time.Date(2022, time.March, 4, 1, 13, 44, 142900000, time.UTC),
+		CorrelationID: "corr-1",
+		SchemaVersion: "2",
+		Fields: []EventField{
+			Field("zeta", "XYZ"),
+			Field("alpha", float64(3)),
+			Field("nested", map[string]interface{}{"foo": "bar"}),
+		},
+	}
+}
+
+func TestCloudEventStructuredRoundTrip(t *testing.T) {
+	want := cloudEventFixture()
+
+	raw, err := MarshalCloudEvent(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Event
+	if err := UnmarshalCloudEvent(raw, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*want, got) {
+		t.Errorf("event did not survive round trip: want=%+v got=%+v", *want, got)
+	}
+}
+
+func TestCloudEventBinaryRoundTrip(t *testing.T) {
+	want := cloudEventFixture()
+
+	attrs, data, err := MarshalCloudEventBinary(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attrs["specversion"] != "1.0" || attrs["type"] != "dataset.ingest.completed" {
+		t.Errorf("unexpected attributes: %+v", attrs)
+	}
+
+	wantData := `{"zeta":"XYZ","alpha":3,"nested":{"foo":"bar"}}`
+	if string(data) != wantData {
+		t.Errorf("unexpected data: want=%s got=%s", wantData, data)
+	}
+
+	var got Event
+	if err := UnmarshalCloudEventBinary(attrs, data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*want, got) {
+		t.Errorf("event did not survive round trip: want=%+v got=%+v", *want, got)
+	}
+}
+
+func TestCloudEventForeignData(t *testing.T) {
+	raw := `{
+  "specversion": "1.0",
+  "id": "1",
+  "source": "https://example.com",
+  "type": "com.example.thing",
+  "data": [1, 2]
+}`
+	var ev Event
+	if err := UnmarshalCloudEvent([]byte(raw), &ev); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []EventField{Field(CloudEventsDataKey, []interface{}{float64(1), float64(2)})}
+	if !reflect.DeepEqual(want, ev.Fields) {
+		t.Errorf("unexpected fields: want=%+v got=%+v", want, ev.Fields)
+	}
+
+	attrs := map[string]string{
+		"specversion":     "1.0",
+		"id":              "2",
+		"source":          "https://example.com",
+		"type":            "com.example.thing",
+		"datacontenttype": "text/plain",
+	}
+	if err := UnmarshalCloudEventBinary(attrs, []byte("hello"), &ev); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, err := ev.StringField(CloudEventsDataKey); err != nil || got != "hello" {
+		t.Errorf("unexpected data field: got=%v err=%v", got, err)
+	}
+
+	structured := `{
+  "specversion": "1.0",
+  "id": "3",
+  "source": "https://example.com",
+  "type": "com.example.thing",
+  "datacontenttype": "text/plain",
+  "data": "hello"
+}`
+	if err := UnmarshalCloudEvent([]byte(structured), &ev); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, err := ev.StringField(CloudEventsDataKey); err != nil || got != "hello" {
+		t.Errorf("unexpected structured data field: got=%q err=%v", got, err)
+	}
+}
+
+func TestCloudEventInvalid(t *testing.T) {
+	tests := []string{
+		`{"specversion": "0.3", "id": "1", "source": "/", "type": "x"}`,
+		`{"specversion": "1.0", "source": "/", "type": "x"}`,
+		`{"specversion": "1.0", "id": "1", "source": "/"}`,
+		`{"specversion": "1.0", "id": "1", "source": "/", "type": "x", "time": "yesterday"}`,
+		`not json`,
+	}
+
+	for i, tt := range tests {
+		var ev Event
+		if err := UnmarshalCloudEvent([]byte(tt), &ev); !errors.Is(err, ErrCloudEventInvalid) {
+			t.Errorf("case %d: expected ErrCloudEventInvalid, got %v", i, err)
+		}
+	}
+}
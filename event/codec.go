@@ -0,0 +1,215 @@
+package event
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// Codec converts events to and from a wire format. Transports record the
+// ContentType alongside each payload so that receivers can select the
+// matching Codec via LookupCodec.
+type Codec interface {
+	ContentType() string
+	Marshal(*Event) ([]byte, error)
+	Unmarshal([]byte, *Event) error
+}
+
+// Compressor wraps the output of another Codec, see CompressedCodec.
+type Compressor interface {
+	// Name is appended to the wrapped codec's content type, e.g. "gzip"
+	// turns "application/json" into "application/json+gzip".
+	Name() string
+	Compress([]byte) ([]byte, error)
+	Decompress([]byte) ([]byte, error)
+}
+
+var (
+	DefaultCodec Codec = &JSONCodec{}
+
+	codecsMu    sync.RWMutex
+	codecs      = make(map[string]Codec)
+	compressors = make(map[string]Compressor)
+)
+
+func init() {
+	RegisterCodec(&JSONCodec{})
+	RegisterCodec(&CloudEventsCodec{})
+	RegisterCodec(&CBORCodec{})
+	RegisterCompressor(&GzipCompressor{})
+}
+
+// RegisterCodec makes a codec available to LookupCodec, replacing any
+// codec previously registered for the same content type.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[normalizeContentType(c.ContentType())] = c
+}
+
+// RegisterCompressor makes a compressor available to LookupCodec, e.g. to
+// add zstd support using a third-party implementation.
+func RegisterCompressor(c Compressor) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	compressors[c.Name()] = c
+}
+
+// LookupCodec finds the codec for the given content type. Compressed
+// content types such as "application/cbor+gzip" resolve to the registered
+// base codec wrapped by the named compressor. An empty content type
+// resolves to DefaultCodec.
+func LookupCodec(contentType string) (Codec, error) {
+	ct := normalizeContentType(contentType)
+	if ct == "" {
+		return DefaultCodec, nil
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	if c, ok := codecs[ct]; ok {
+		return c, nil
+	}
+
+	if i := strings.LastIndex(ct, "+"); i > 0 {
+		if comp, ok := compressors[ct[i+1:]]; ok {
+			if base, ok := codecs[ct[:i]]; ok {
+				return CompressedCodec(base, comp), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no event codec registered for content type %q", contentType)
+}
+
+func normalizeContentType(ct string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+}
+
+// JSONCodec is the native {"type": ..., "fields": [...]} JSON encoding.
+type JSONCodec struct {
+	// DuplicateKeys is applied to every decoded event
+	DuplicateKeys DuplicateKeyPolicy
+}
+
+func (c *JSONCodec) ContentType() string { return "application/json" }
+
+func (c *JSONCodec) Marshal(ev *Event) ([]byte, error) {
+	return json.Marshal(ev)
+}
+
+func (c *JSONCodec) Unmarshal(data []byte, ev *Event) error {
+	if err := json.Unmarshal(data, ev); err != nil {
+		return err
+	}
+	return ev.Dedupe(c.DuplicateKeys)
+}
+
+// CloudEventsCodec is the CloudEvents 1.0 structured JSON encoding.
+type CloudEventsCodec struct{}
+
+func (c *CloudEventsCodec) ContentType() string { return CloudEventsContentType }
+
+func (c *CloudEventsCodec) Marshal(ev *Event) ([]byte, error) {
+	return MarshalCloudEvent(ev)
+}
+
+func (c *CloudEventsCodec) Unmarshal(data []byte, ev *Event) error {
+	return UnmarshalCloudEvent(data, ev)
+}
+
+// CompressedCodec compresses the output of another codec.
+func CompressedCodec(c Codec, comp Compressor) Codec {
+	return &compressedCodec{codec: c, comp: comp}
+}
+
+type compressedCodec struct {
+	codec Codec
+	comp  Compressor
+}
+
+func (c *compressedCodec) ContentType() string {
+	return c.codec.ContentType() + "+" + c.comp.Name()
+}
+
+func (c *compressedCodec) Marshal(ev *Event) ([]byte, error) {
+	data, err := c.codec.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return c.comp.Compress(data)
+}
+
+func (c *compressedCodec) Unmarshal(data []byte, ev *Event) error {
+	raw, err := c.comp.Decompress(data)
+	if err != nil {
+		return fmt.Errorf("failed decompressing event: %v", err)
+	}
+	return c.codec.Unmarshal(raw, ev)
+}
+
+// DefaultMaxDecompressedSize bounds the output of GzipCompressor.Decompress
+// unless MaxDecompressedSize is set.
+const DefaultMaxDecompressedSize = 64 << 20
+
+var ErrDecompressedSizeExceeded = errors.New("decompressed size limit exceeded")
+
+type GzipCompressor struct {
+	// Level is a compress/gzip compression level; zero selects the default
+	Level int
+
+	// MaxDecompressedSize caps the number of bytes Decompress will produce,
+	// guarding receivers against small payloads that inflate without
+	// bound; zero selects DefaultMaxDecompressedSize
+	MaxDecompressedSize int64
+}
+
+func (c *GzipCompressor) Name() string { return "gzip" }
+
+func (c *GzipCompressor) Compress(data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	max := c.MaxDecompressedSize
+	if max <= 0 {
+		max = DefaultMaxDecompressedSize
+	}
+	raw, err := ioutil.ReadAll(io.LimitReader(zr, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > max {
+		return nil, ErrDecompressedSizeExceeded
+	}
+	return raw, nil
+}
@@ -0,0 +1,190 @@
+package event
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func codecFixture() *Event {
+	return &Event{
+		ID:     "abc-123",
+		Type:   EventType("example_type"),
+		Source: "/example",
+		Time:   time.Date(2022, time.March, 4, 1, 13, 44, 142900000, time.UTC),
+		Fields: []EventField{
+			Field("example_str", "XYZ"),
+			Field("example_float", 12.43),
+			Field("example_bool", true),
+			Field("example_null", nil),
+			Field("example_json", map[string]interface{}{"foo": "XYZ", "bar": []interface{}{"a"}}),
+		},
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codecs := []Codec{
+		&JSONCodec{},
+		&CloudEventsCodec{},
+		&CBORCodec{},
+		CompressedCodec(&JSONCodec{}, &GzipCompressor{}),
+		CompressedCodec(&CBORCodec{}, &GzipCompressor{}),
+	}
+
+	for _, codec := range codecs {
+		want := codecFixture()
+
+		data, err := codec.Marshal(want)
+		if err != nil {
+			t.Fatalf("%s: failed marshaling: %v", codec.ContentType(), err)
+		}
+
+		var got Event
+		if err := codec.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: failed unmarshaling: %v", codec.ContentType(), err)
+		}
+
+		if !reflect.DeepEqual(*want, got) {
+			t.Errorf("%s: event did not survive round trip: want=%+v got=%+v", codec.ContentType(), *want, got)
+		}
+	}
+}
+
+func TestCodecCompression(t *testing.T) {
+	blob := bytes.Repeat([]byte("climate "), 1024)
+	ev := NewEvent(EventType("example_type"), Field("example_blob", string(blob)))
+
+	plain, err := (&JSONCodec{}).Marshal(ev)
+	if err != nil {
+		t.Fatalf("failed marshaling: %v", err)
+	}
+
+	compressed, err := CompressedCodec(&JSONCodec{}, &GzipCompressor{}).Marshal(ev)
+	if err != nil {
+		t.Fatalf("failed marshaling: %v", err)
+	}
+
+	if len(compressed) >= len(plain) {
+		t.Errorf("expected compressed payload to be smaller: plain=%d compressed=%d", len(plain), len(compressed))
+	}
+}
+
+func TestGzipDecompressLimit(t *testing.T) {
+	gz := &GzipCompressor{MaxDecompressedSize: 1024}
+	compressed, err := gz.Compress(bytes.Repeat([]byte{0}, 1<<20))
+	if err != nil {
+		t.Fatalf("failed compressing: %v", err)
+	}
+
+	if _, err := gz.Decompress(compressed); err != ErrDecompressedSizeExceeded {
+		t.Errorf("unexpected error: want=%v got=%v", ErrDecompressedSizeExceeded, err)
+	}
+
+	compressed, err = gz.Compress(bytes.Repeat([]byte{0}, 1024))
+	if err != nil {
+		t.Fatalf("failed compressing: %v", err)
+	}
+	raw, err := gz.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(raw) != 1024 {
+		t.Errorf("unexpected length: want=1024 got=%d", len(raw))
+	}
+}
+
+func TestCBORCodecIntegers(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("example_big", int64(9007199254740993)),
+		Field("example_neg", -42),
+		Field("example_bytes", []byte{0x01, 0x02}),
+		Field("example_slice", []string{"a", "b"}),
+		Field("example_struct", jsonMessage{Foo: "XYZ", Bar: 1}),
+	)
+
+	codec := &CBORCodec{}
+	data, err := codec.Marshal(ev)
+	if err != nil {
+		t.Fatalf("failed marshaling: %v", err)
+	}
+
+	var got Event
+	if err := codec.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed unmarshaling: %v", err)
+	}
+
+	if v, err := got.Int64Field(EventFieldKey("example_big")); err != nil || v != 9007199254740993 {
+		t.Errorf("unexpected int64 result: got=%v err=%v", v, err)
+	}
+	if v, err := got.IntField(EventFieldKey("example_neg")); err != nil || v != -42 {
+		t.Errorf("unexpected int result: got=%v err=%v", v, err)
+	}
+	if v, _ := got.Field(EventFieldKey("example_bytes")); !reflect.DeepEqual([]byte{0x01, 0x02}, v) {
+		t.Errorf("unexpected bytes result: got=%v", v)
+	}
+	if v, err := got.StringSliceField(EventFieldKey("example_slice")); err != nil || !reflect.DeepEqual([]string{"a", "b"}, v) {
+		t.Errorf("unexpected slice result: got=%v err=%v", v, err)
+	}
+
+	var msg jsonMessage
+	if err := got.JSONField(EventFieldKey("example_struct"), &msg); err != nil || msg.Foo != "XYZ" {
+		t.Errorf("unexpected struct result: got=%+v err=%v", msg, err)
+	}
+}
+
+func TestCBORCodecMalformed(t *testing.T) {
+	tests := [][]byte{
+		{},
+		{0xa1}, // map missing its entry
+		{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // absurd array length
+		{0x01},       // not a map
+		{0xa0, 0x00}, // trailing data
+	}
+
+	for i, tt := range tests {
+		var ev Event
+		if err := (&CBORCodec{}).Unmarshal(tt, &ev); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestCBORHalfFloat(t *testing.T) {
+	dec := cborDecoder{buf: []byte{0xf9, 0x3e, 0x00}}
+	v, err := dec.decode(0)
+	if err != nil || v != 1.5 {
+		t.Errorf("unexpected half float result: got=%v err=%v", v, err)
+	}
+}
+
+func TestLookupCodec(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"", "application/json"},
+		{"application/json; charset=utf-8", "application/json"},
+		{"application/cloudevents+json", "application/cloudevents+json"},
+		{"application/cbor", "application/cbor"},
+		{"application/cbor+gzip", "application/cbor+gzip"},
+		{"Application/JSON+GZIP", "application/json+gzip"},
+	}
+
+	for _, tt := range tests {
+		codec, err := LookupCodec(tt.contentType)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.contentType, err)
+			continue
+		}
+		if got := codec.ContentType(); got != tt.want {
+			t.Errorf("%q: unexpected codec: want=%s got=%s", tt.contentType, tt.want, got)
+		}
+	}
+
+	for _, ct := range []string{"text/plain", "application/json+zstd"} {
+		if _, err := LookupCodec(ct); err == nil {
+			t.Errorf("%q: expected error", ct)
+		}
+	}
+}
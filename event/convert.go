@@ -0,0 +1,132 @@
+package event
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// bounds of the platform int, as math.MinInt and math.MaxInt require Go 1.17
+const (
+	maxInt = 1<<(strconv.IntSize-1) - 1
+	minInt = -1 << (strconv.IntSize - 1)
+)
+
+// Field values decoded from JSON are always float64, but events built
+// in-process via Field() may carry any native numeric type. The helpers
+// below normalize both cases.
+
+func toFloat64(val interface{}) (float64, error) {
+	switch nv := val.(type) {
+	case float64:
+		return nv, nil
+	case float32:
+		return float64(nv), nil
+	case int:
+		return float64(nv), nil
+	case int8:
+		return float64(nv), nil
+	case int16:
+		return float64(nv), nil
+	case int32:
+		return float64(nv), nil
+	case int64:
+		return float64(nv), nil
+	case uint:
+		return float64(nv), nil
+	case uint8:
+		return float64(nv), nil
+	case uint16:
+		return float64(nv), nil
+	case uint32:
+		return float64(nv), nil
+	case uint64:
+		return float64(nv), nil
+	case json.Number:
+		fv, err := nv.Float64()
+		if err != nil {
+			return 0, ErrFieldUnexpectedValue
+		}
+		return fv, nil
+	}
+	return 0, ErrFieldIncorrectType
+}
+
+func toInt64(val interface{}) (int64, error) {
+	switch nv := val.(type) {
+	case int:
+		return int64(nv), nil
+	case int8:
+		return int64(nv), nil
+	case int16:
+		return int64(nv), nil
+	case int32:
+		return int64(nv), nil
+	case int64:
+		return nv, nil
+	case uint:
+		return uintToInt64(uint64(nv))
+	case uint8:
+		return int64(nv), nil
+	case uint16:
+		return int64(nv), nil
+	case uint32:
+		return int64(nv), nil
+	case uint64:
+		return uintToInt64(nv)
+	case json.Number:
+		if iv, err := nv.Int64(); err == nil {
+			return iv, nil
+		}
+	}
+
+	fv, err := toFloat64(val)
+	if err != nil {
+		return 0, err
+	}
+	return floatToInt64(fv)
+}
+
+func toUint64(val interface{}) (uint64, error) {
+	switch nv := val.(type) {
+	case uint:
+		return uint64(nv), nil
+	case uint8:
+		return uint64(nv), nil
+	case uint16:
+		return uint64(nv), nil
+	case uint32:
+		return uint64(nv), nil
+	case uint64:
+		return nv, nil
+	}
+
+	iv, err := toInt64(val)
+	if err != nil {
+		return 0, err
+	}
+	if iv < 0 {
+		return 0, ErrFieldUnexpectedValue
+	}
+	return uint64(iv), nil
+}
+
+func uintToInt64(uv uint64) (int64, error) {
+	if uv > math.MaxInt64 {
+		return 0, ErrFieldUnexpectedValue
+	}
+	return int64(uv), nil
+}
+
+// floatToInt64 rejects values that would otherwise be silently truncated
+// or overflow, e.g. 3.7 or 1e300.
+func floatToInt64(fv float64) (int64, error) {
+	if math.IsNaN(fv) || math.IsInf(fv, 0) || fv != math.Trunc(fv) {
+		return 0, ErrFieldUnexpectedValue
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which is itself out of range
+	if fv < math.MinInt64 || fv >= math.MaxInt64 {
+		return 0, ErrFieldUnexpectedValue
+	}
+	return int64(fv), nil
+}
@@ -0,0 +1,236 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DeadLetter records an event that a handler failed to process.
+type DeadLetter struct {
+	Event   *Event `json:"event"`
+	Handler string `json:"handler"`
+	// Route is the ID of the route the handler was mounted as, if it was
+	// dispatched by an EventRouter, see Route.ID
+	Route    int       `json:"route,omitempty"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// DeadLetterSink parks dead letters for later inspection and replay.
+type DeadLetterSink interface {
+	WriteDeadLetter(context.Context, *DeadLetter) error
+}
+
+// DeadLetters parks events that the wrapped handler fails to process in
+// sink, reporting success to the caller once parked. If the sink itself
+// fails, the handler's original error is returned. Use it outside Retry,
+// e.g. router.Use(DeadLetters(sink, logger), Retry(cfg)), so that events
+// are only parked once retries are exhausted. Events that fail again while
+// being replayed are not parked, their error is returned to the replayer.
+//
+// The sink is written to with the handler's context values but not its
+// cancellation, which has often already fired, e.g. after a handler
+// timeout; writes are instead limited to DeadLetterWriteTimeout.
+func DeadLetters(sink DeadLetterSink, logger *zap.Logger) Middleware {
+	return func(next EventHandler) EventHandler {
+		name := handlerName(next)
+		return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+			err := next.HandleEvent(ctx, ev)
+			if !isHandlerFailure(err) || isReplay(ctx) {
+				return err
+			}
+
+			dl := &DeadLetter{
+				Event:    ev,
+				Handler:  name,
+				Error:    err.Error(),
+				Attempts: 1,
+				Time:     time.Now().UTC(),
+			}
+			if rt := RouteFromContext(ctx); rt != nil {
+				dl.Handler = rt.Name()
+				dl.Route = rt.id
+			}
+			var rerr *RetryError
+			if errors.As(err, &rerr) {
+				dl.Attempts = rerr.Attempts
+			}
+
+			wctx, cancel := context.WithTimeout(detachedContext{ctx}, DeadLetterWriteTimeout)
+			serr := sink.WriteDeadLetter(wctx, dl)
+			cancel()
+			if serr != nil {
+				logger.Error("failed writing dead letter",
+					zap.String("handler", name),
+					zap.String("type", string(ev.Type)),
+					zap.Error(serr),
+				)
+				return err
+			}
+
+			logger.Warn("event dead-lettered",
+				zap.String("handler", name),
+				zap.String("type", string(ev.Type)),
+				zap.String("event_id", ev.ID),
+				zap.Error(err),
+			)
+			return nil
+		})
+	}
+}
+
+// DeadLetterWriteTimeout bounds each write by DeadLetters to its sink.
+var DeadLetterWriteTimeout = 30 * time.Second
+
+// detachedContext carries the values of its parent without its deadline or
+// cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// FileDeadLetterSink appends dead letters to a file as JSON lines, which
+// may be replayed with ReplayDeadLetters.
+type FileDeadLetterSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeadLetterSink{f: f}, nil
+}
+
+func (s *FileDeadLetterSink) WriteDeadLetter(ctx context.Context, dl *DeadLetter) error {
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(data, '\n'))
+	return err
+}
+
+func (s *FileDeadLetterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// ReplayDeadLetters reads dead letters written as JSON lines, e.g. by a
+// FileDeadLetterSink, and passes each event to eh, see ReplayDeadLetter.
+// Replay stops at the first error, returning the number of events
+// replayed before it.
+func ReplayDeadLetters(ctx context.Context, r io.Reader, eh EventHandler) (int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64*1024*1024)
+
+	n := 0
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		var dl DeadLetter
+		if err := json.Unmarshal(sc.Bytes(), &dl); err != nil {
+			return n, fmt.Errorf("failed decoding dead letter on line %d: %v", line, err)
+		}
+		if dl.Event == nil {
+			return n, fmt.Errorf("dead letter on line %d has no event", line)
+		}
+
+		if err := ReplayDeadLetter(ctx, eh, &dl); err != nil {
+			return n, fmt.Errorf("failed replaying event %s from line %d: %w", dl.Event.ID, line, err)
+		}
+		n++
+	}
+
+	return n, sc.Err()
+}
+
+type replayKey struct{}
+
+func isReplay(ctx context.Context) bool {
+	return ctx.Value(replayKey{}) != nil
+}
+
+// ReplayDeadLetter passes the event of dl to eh, typically an EventRouter.
+// A router passes it only to the handler that failed it, see
+// HandleEventRoute, unless the dead letter was not recorded under a router,
+// and reports any handler failure regardless of its ErrorPolicy. A failure
+// is returned rather than parked again by DeadLetters.
+func ReplayDeadLetter(ctx context.Context, eh EventHandler, dl *DeadLetter) error {
+	ctx = context.WithValue(ctx, replayKey{}, true)
+
+	er, ok := eh.(*EventRouter)
+	if !ok {
+		return eh.HandleEvent(ctx, dl.Event)
+	}
+	if dl.Route == 0 {
+		return replayToRoutes(ctx, er, dl.Event)
+	}
+
+	rt := deadLetterRoute(er, dl)
+	if rt == nil {
+		return fmt.Errorf("no route mounted for handler %s", dl.Handler)
+	}
+	return er.HandleEventRoute(ctx, rt, dl.Event)
+}
+
+// replayToRoutes passes ev to every handler matching it, returning the
+// first failure.
+func replayToRoutes(ctx context.Context, er *EventRouter, ev *Event) error {
+	for _, rt := range er.handlersFor(ev, er.Logger) {
+		err := er.call(ctx, rt, ev)
+		if err == ErrStopPropagation {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadLetterRoute finds the route that failed dl by route ID and handler
+// name, or by handler name alone if it is unambiguous, as route IDs change
+// when handlers are mounted in a different order, e.g. after a restart.
+func deadLetterRoute(er *EventRouter, dl *DeadLetter) *Route {
+	var named []*Route
+	seen := make(map[int]bool)
+	for _, routes := range er.Routes() {
+		for _, rt := range routes {
+			if seen[rt.id] || rt.Name() != dl.Handler {
+				continue
+			}
+			seen[rt.id] = true
+			if rt.id == dl.Route {
+				return rt
+			}
+			named = append(named, rt)
+		}
+	}
+	if len(named) == 1 {
+		return named[0]
+	}
+	return nil
+}
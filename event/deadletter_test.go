@@ -0,0 +1,226 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type memoryDeadLetterSink struct {
+	err     error
+	letters []*DeadLetter
+}
+
+func (s *memoryDeadLetterSink) WriteDeadLetter(ctx context.Context, dl *DeadLetter) error {
+	if s.err != nil {
+		return s.err
+	}
+	// like a real sink, fail writes under a canceled context
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.letters = append(s.letters, dl)
+	return nil
+}
+
+func TestDeadLetters(t *testing.T) {
+	sink := &memoryDeadLetterSink{}
+	errTransient := errors.New("transient")
+
+	eh := Chain(&flakyHandler{failures: 5, err: errTransient},
+		DeadLetters(sink, zap.NewNop()),
+		Retry(RetryConfig{MaxAttempts: 2, InitialBackoff: time.Microsecond}),
+	)
+
+	ev := &Event{ID: "abc-123", Type: EventType("test")}
+	if err := eh.HandleEvent(context.Background(), ev); err != nil {
+		t.Fatalf("expected dead-lettered event to succeed, got %v", err)
+	}
+
+	if len(sink.letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(sink.letters))
+	}
+	dl := sink.letters[0]
+	if dl.Event != ev || dl.Handler != "*event.flakyHandler" || dl.Attempts != 2 || dl.Time.IsZero() {
+		t.Errorf("unexpected dead letter: %+v", dl)
+	}
+	if !strings.Contains(dl.Error, "transient") {
+		t.Errorf("unexpected dead letter error: %v", dl.Error)
+	}
+}
+
+func TestDeadLettersSinkFailure(t *testing.T) {
+	errTransient := errors.New("transient")
+	sink := &memoryDeadLetterSink{err: errors.New("disk full")}
+
+	eh := DeadLetters(sink, zap.NewNop())(&flakyHandler{failures: 1, err: errTransient})
+	if err := eh.HandleEvent(context.Background(), &Event{}); err != errTransient {
+		t.Errorf("expected original error when sink fails, got %v", err)
+	}
+}
+
+func TestDeadLettersSkipsSuccess(t *testing.T) {
+	sink := &memoryDeadLetterSink{}
+
+	for _, err := range []error{nil, ErrStopPropagation} {
+		eh := DeadLetters(sink, zap.NewNop())(&flakyHandler{failures: 1, err: err})
+		if got := eh.HandleEvent(context.Background(), &Event{}); got != err {
+			t.Errorf("unexpected error: want=%v got=%v", err, got)
+		}
+	}
+	if len(sink.letters) != 0 {
+		t.Errorf("unexpected dead letters: %+v", sink.letters)
+	}
+}
+
+func TestFileDeadLetterSinkReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+
+	sink, err := NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events := []*Event{
+		{ID: "a", Type: EventType("test"), Fields: []EventField{Field("name", "XYZ")}},
+		{ID: "b", Type: EventType("test"), Fields: []EventField{Field("count", float64(3))}},
+	}
+	for _, ev := range events {
+		dl := &DeadLetter{Event: ev, Handler: "h", Error: "failed", Attempts: 3, Time: time.Now().UTC()}
+		if err := sink.WriteDeadLetter(context.Background(), dl); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	er := NewEventRouter(zap.NewNop())
+	eh := &fixtureHandler{}
+	er.Mount(eh)
+
+	n, err := ReplayDeadLetters(context.Background(), f, er)
+	if err != nil || n != 2 {
+		t.Fatalf("unexpected replay result: n=%d err=%v", n, err)
+	}
+
+	want := []Event{*events[0], *events[1]}
+	if !reflect.DeepEqual(want, eh.events) {
+		t.Errorf("unexpected replayed events: want=%+v got=%+v", want, eh.events)
+	}
+}
+
+func TestReplayDeadLettersToRoute(t *testing.T) {
+	sink := &memoryDeadLetterSink{}
+
+	er := NewEventRouter(zap.NewNop())
+	er.Use(DeadLetters(sink, zap.NewNop()))
+	ok := &fixtureHandler{}
+	failing := &fixtureHandler{err: errors.New("failed")}
+	er.Mount(ok)
+	rt := er.Mount(failing)
+
+	if err := er.HandleEvent(context.Background(), &Event{ID: "a", Type: EventType("test")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sink.letters) != 1 || sink.letters[0].Route != rt.ID() {
+		t.Fatalf("expected 1 dead letter for route %d, got %+v", rt.ID(), sink.letters)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(sink.letters[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// handlers of the same type are told apart by route ID
+	failing.err = nil
+	if n, err := ReplayDeadLetters(context.Background(), &buf, er); err != nil || n != 1 {
+		t.Fatalf("unexpected replay result: n=%d err=%v", n, err)
+	}
+	if len(ok.events) != 1 || len(failing.events) != 2 {
+		t.Errorf("expected replay to reach only the failed handler: ok=%d failing=%d", len(ok.events), len(failing.events))
+	}
+}
+
+func TestReplayDeadLettersStopsOnError(t *testing.T) {
+	input := `{"event":{"id":"a","type":"test"},"handler":"h"}
+{"event":{"id":"b","type":"test"},"handler":"h"}
+`
+	eh := &fixtureHandler{err: errors.New("still failing")}
+
+	n, err := ReplayDeadLetters(context.Background(), strings.NewReader(input), eh)
+	if n != 0 || !errors.Is(err, eh.err) {
+		t.Errorf("unexpected replay result: n=%d err=%v", n, err)
+	}
+	if len(eh.events) != 1 {
+		t.Errorf("expected replay to stop after first failure, got %d events", len(eh.events))
+	}
+
+	if _, err := ReplayDeadLetters(context.Background(), bytes.NewBufferString("{not json\n"), eh); err == nil {
+		t.Errorf("expected error decoding malformed dead letter")
+	}
+}
+
+func TestReplayDeadLettersStillFailing(t *testing.T) {
+	sink := &memoryDeadLetterSink{}
+
+	er := NewEventRouter(zap.NewNop())
+	er.Use(DeadLetters(sink, zap.NewNop()))
+	failing := &fixtureHandler{err: errors.New("failed")}
+	er.Mount(failing)
+
+	if err := er.HandleEvent(context.Background(), &Event{ID: "a", Type: EventType("test")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sink.letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(sink.letters))
+	}
+
+	// the letter recorded under the router, and one recorded without a
+	// route, which is replayed to every matching handler
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(sink.letters[0])
+	unrouted := *sink.letters[0]
+	unrouted.Route = 0
+
+	n, err := ReplayDeadLetters(context.Background(), &buf, er)
+	if n != 0 || !errors.Is(err, failing.err) {
+		t.Errorf("unexpected replay result: n=%d err=%v", n, err)
+	}
+	if err := ReplayDeadLetter(context.Background(), er, &unrouted); !errors.Is(err, failing.err) {
+		t.Errorf("unexpected replay result: err=%v", err)
+	}
+	if len(sink.letters) != 1 {
+		t.Errorf("expected failed replays not to be parked again, got %d dead letters", len(sink.letters))
+	}
+}
+
+func TestDeadLettersCanceledContext(t *testing.T) {
+	sink := &memoryDeadLetterSink{}
+	eh := DeadLetters(sink, zap.NewNop())(&fixtureHandler{err: context.DeadlineExceeded})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := eh.HandleEvent(ctx, &Event{ID: "a"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(sink.letters) != 1 {
+		t.Errorf("expected dead letter to be written after handler context ended, got %d", len(sink.letters))
+	}
+}
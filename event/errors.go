@@ -0,0 +1,74 @@
+package event
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is returned by the DetailedEvent field accessors. It names the
+// offending key and matches the relevant sentinel (ErrFieldMissing,
+// ErrFieldIncorrectType or ErrFieldUnexpectedValue) via errors.Is.
+type FieldError struct {
+	Key EventFieldKey
+
+	// Want describes the type the caller asked for, and Got the Go type
+	// of the value actually found. Both are empty for missing fields.
+	Want string
+	Got  string
+
+	// Err is one of the ErrField* sentinels
+	Err error
+
+	// Cause is the underlying error, if any, e.g. from time.Parse
+	Cause error
+}
+
+func newFieldError(key EventFieldKey, want string, val interface{}, err error, cause error) *FieldError {
+	return &FieldError{
+		Key:   key,
+		Want:  want,
+		Got:   fmt.Sprintf("%T", val),
+		Err:   err,
+		Cause: cause,
+	}
+}
+
+func (e *FieldError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: key=%s", e.Err, e.Key)
+	if e.Want != "" {
+		fmt.Fprintf(&b, " want=%s got=%s", e.Want, e.Got)
+	}
+	if e.Cause != nil {
+		fmt.Fprintf(&b, ": %v", e.Cause)
+	}
+	return b.String()
+}
+
+func (e *FieldError) Is(target error) bool {
+	return target == e.Err
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Cause
+}
+
+// DetailedEvent exposes the same field accessors as Event, but reports
+// failures as a *FieldError rather than the bare ErrField* sentinel.
+type DetailedEvent struct {
+	*Event
+}
+
+// Detailed returns a view of ev whose field accessors return *FieldError.
+func (ev *Event) Detailed() DetailedEvent {
+	return DetailedEvent{Event: ev}
+}
+
+// bareFieldError reduces a *FieldError to its sentinel, as returned by the
+// Event field accessors.
+func bareFieldError(err error) error {
+	if ferr, ok := err.(*FieldError); ok {
+		return ferr.Err
+	}
+	return err
+}
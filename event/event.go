@@ -1,9 +1,11 @@
 package event
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -11,82 +13,367 @@ var (
 	ErrFieldMissing         = errors.New("event field not found")
 	ErrFieldIncorrectType   = errors.New("event field is of incorrect type")
 	ErrFieldUnexpectedValue = errors.New("event field contains unexpected value")
+	ErrFieldDuplicate       = errors.New("event field is duplicated")
 )
 
 type EventType string
 type EventFieldKey string
 
+// NewEvent builds an event of the given type, assigning it a unique ID and
+// stamping it with the current time.
 func NewEvent(typ EventType, fields ...EventField) *Event {
-	return &Event{
+	ev := &Event{
+		ID:     NewEventID(),
 		Type:   typ,
+		Time:   time.Now().UTC(),
 		Fields: fields,
 	}
+	ev.resetIndex()
+	return ev
+}
+
+// NewEventFrom builds an event caused by parent, carrying forward its
+// correlation ID (or starting one from the parent's ID if it has none).
+func NewEventFrom(parent *Event, typ EventType, fields ...EventField) *Event {
+	ev := NewEvent(typ, fields...)
+	ev.CausationID = parent.ID
+	ev.CorrelationID = parent.CorrelationID
+	if ev.CorrelationID == "" {
+		ev.CorrelationID = parent.ID
+	}
+	return ev
+}
+
+// NewEventID generates a random (version 4) UUID.
+func NewEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand should never fail in practice, but fall back to
+		// something unique-enough rather than panicking
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
 type Event struct {
-	Type   EventType    `json:"type"`
+	// Envelope metadata; every field is optional so that events produced
+	// before it existed continue to decode.
+	ID            string    `json:"id,omitempty"`
+	Type          EventType `json:"type"`
+	Source        string    `json:"source,omitempty"`
+	Time          time.Time `json:"time"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	CausationID   string    `json:"causation_id,omitempty"`
+	SchemaVersion string    `json:"schema_version,omitempty"`
+
 	Fields []EventField `json:"fields"`
+
+	// see index.go
+	index *fieldIndexHolder
 }
 
+// MarshalJSON has a value receiver so that Event values, e.g. in a []Event,
+// encode the same as a *Event. Copying an event is safe while other
+// goroutines read it, as the field index lives behind a pointer.
+func (ev Event) MarshalJSON() ([]byte, error) {
+	type alias Event
+
+	//NOTE: encoding/json cannot omit a zero time.Time, so shadow it
+	// with a pointer that is only set when a timestamp is present
+	aux := struct {
+		*alias
+		Time *time.Time `json:"time,omitempty"`
+	}{alias: (*alias)(&ev)}
+
+	if !ev.Time.IsZero() {
+		aux.Time = &ev.Time
+	}
+
+	return json.Marshal(aux)
+}
+
+func (ev *Event) UnmarshalJSON(data []byte) error {
+	type alias Event
+	if err := json.Unmarshal(data, (*alias)(ev)); err != nil {
+		return err
+	}
+	ev.resetIndex()
+	return nil
+}
+
+// Field returns the value of the first field with the given key. The key
+// may also be a path to a value nested within a field, see path.go.
 func (ev *Event) Field(key EventFieldKey) (interface{}, error) {
+	val, err := ev.Detailed().Field(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) Field(key EventFieldKey) (interface{}, error) {
+	val, err := ev.rawField(key)
+	if err != nil {
+		return nil, err
+	}
+	return resolveValue(key, val)
+}
+
+// rawField behaves like Field, but returns json.RawMessage values from a
+// lazily-decoded event as-is.
+func (ev *Event) rawField(key EventFieldKey) (interface{}, error) {
+	if val, ok := ev.lookup(key); ok {
+		return val, nil
+	}
+	if isPath(key) {
+		return ev.lookupPath(key)
+	}
+	return nil, &FieldError{Key: key, Err: ErrFieldMissing}
+}
+
+func (ev *Event) lookup(key EventFieldKey) (interface{}, bool) {
+	if i, ok, indexed := ev.indexOf(key); indexed {
+		if !ok {
+			return nil, false
+		}
+		return ev.Fields[i].Value, true
+	}
 	for _, ef := range ev.Fields {
 		if ef.Key == key {
-			return ef.Value, nil
+			return ef.Value, true
 		}
 	}
-	return nil, ErrFieldMissing
+	return nil, false
 }
 
 func (ev *Event) IntField(key EventFieldKey) (int, error) {
+	val, err := ev.Detailed().IntField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) IntField(key EventFieldKey) (int, error) {
 	val, err := ev.Field(key)
 	if err != nil {
 		return 0, err
 	}
-	fv, ok := val.(float64)
-	if !ok {
-		return 0, ErrFieldIncorrectType
+	iv, err := toInt64(val)
+	if err == nil && (iv < minInt || iv > maxInt) {
+		err = ErrFieldUnexpectedValue
+	}
+	if err != nil {
+		return 0, newFieldError(key, "int", val, err, nil)
+	}
+	return int(iv), nil
+}
+
+func (ev *Event) Int64Field(key EventFieldKey) (int64, error) {
+	val, err := ev.Detailed().Int64Field(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) Int64Field(key EventFieldKey) (int64, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return 0, err
+	}
+	iv, err := toInt64(val)
+	if err != nil {
+		return 0, newFieldError(key, "int64", val, err, nil)
 	}
-	iv := int(fv)
 	return iv, nil
 }
 
+func (ev *Event) Uint64Field(key EventFieldKey) (uint64, error) {
+	val, err := ev.Detailed().Uint64Field(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) Uint64Field(key EventFieldKey) (uint64, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return 0, err
+	}
+	uv, err := toUint64(val)
+	if err != nil {
+		return 0, newFieldError(key, "uint64", val, err, nil)
+	}
+	return uv, nil
+}
+
+func (ev *Event) FloatField(key EventFieldKey) (float64, error) {
+	val, err := ev.Detailed().FloatField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) FloatField(key EventFieldKey) (float64, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return 0, err
+	}
+	fv, err := toFloat64(val)
+	if err != nil {
+		return 0, newFieldError(key, "float64", val, err, nil)
+	}
+	return fv, nil
+}
+
+func (ev *Event) BoolField(key EventFieldKey) (bool, error) {
+	val, err := ev.Detailed().BoolField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) BoolField(key EventFieldKey) (bool, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return false, err
+	}
+	bv, ok := val.(bool)
+	if !ok {
+		return false, newFieldError(key, "bool", val, ErrFieldIncorrectType, nil)
+	}
+	return bv, nil
+}
+
+// DurationField accepts either a string understood by time.ParseDuration
+// (e.g. "1m30s") or an integer number of nanoseconds, which is how
+// encoding/json represents a time.Duration.
+func (ev *Event) DurationField(key EventFieldKey) (time.Duration, error) {
+	val, err := ev.Detailed().DurationField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) DurationField(key EventFieldKey) (time.Duration, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return 0, err
+	}
+
+	switch tv := val.(type) {
+	case time.Duration:
+		return tv, nil
+	case string:
+		dv, err := time.ParseDuration(tv)
+		if err != nil {
+			return 0, newFieldError(key, "duration", val, ErrFieldUnexpectedValue, err)
+		}
+		return dv, nil
+	}
+
+	iv, err := toInt64(val)
+	if err != nil {
+		return 0, newFieldError(key, "duration", val, err, nil)
+	}
+	return time.Duration(iv), nil
+}
+
 func (ev *Event) StringField(key EventFieldKey) (string, error) {
+	val, err := ev.Detailed().StringField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) StringField(key EventFieldKey) (string, error) {
 	val, err := ev.Field(key)
 	if err != nil {
 		return "", err
 	}
 	sv, ok := val.(string)
 	if !ok {
-		return "", ErrFieldIncorrectType
+		return "", newFieldError(key, "string", val, ErrFieldIncorrectType, nil)
 	}
 	return sv, nil
 }
 
+// DefaultTimeLayouts are the string formats accepted by TimeField, tried
+// in order.
+var DefaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02",
+}
+
+// epochMillisThreshold separates Unix timestamps in seconds from those in
+// milliseconds. As seconds it is in the year 5138, as milliseconds 1973.
+const epochMillisThreshold = 1e11
+
+// TimeField reads a timestamp given either as a string in one of
+// DefaultTimeLayouts or as a number of seconds or milliseconds since the
+// Unix epoch. The zero time is rejected.
 func (ev *Event) TimeField(key EventFieldKey) (time.Time, error) {
+	val, err := ev.Detailed().TimeField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) TimeField(key EventFieldKey) (time.Time, error) {
+	return ev.TimeFieldWithLayout(key, DefaultTimeLayouts...)
+}
+
+// TimeFieldWithLayout behaves like TimeField, but parses string values using
+// the provided layouts (see time.Parse) instead of DefaultTimeLayouts.
+func (ev *Event) TimeFieldWithLayout(key EventFieldKey, layouts ...string) (time.Time, error) {
+	val, err := ev.Detailed().TimeFieldWithLayout(key, layouts...)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) TimeFieldWithLayout(key EventFieldKey, layouts ...string) (time.Time, error) {
 	val, err := ev.Field(key)
 	if err != nil {
 		return time.Time{}, err
 	}
-	sv, ok := val.(string)
-	if !ok {
-		return time.Time{}, ErrFieldIncorrectType
-	}
-	tv, err := time.Parse(time.RFC3339, sv)
-	if err != nil {
-		return time.Time{}, ErrFieldUnexpectedValue
+
+	var tv time.Time
+	switch v := val.(type) {
+	case time.Time:
+		tv = v
+	case string:
+		for _, layout := range layouts {
+			if tv, err = time.Parse(layout, v); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return time.Time{}, newFieldError(key, "time", val, ErrFieldUnexpectedValue, err)
+		}
+	default:
+		fv, err := toFloat64(val)
+		if err != nil {
+			return time.Time{}, newFieldError(key, "time", val, err, nil)
+		}
+		if math.IsNaN(fv) || math.IsInf(fv, 0) {
+			return time.Time{}, newFieldError(key, "time", val, ErrFieldUnexpectedValue, nil)
+		}
+		whole, frac := math.Modf(fv)
+		if math.Abs(fv) >= epochMillisThreshold {
+			tv = time.Unix(0, 0).Add(time.Duration(whole) * time.Millisecond)
+			tv = tv.Add(time.Duration(math.Round(frac * 1e6))).UTC()
+		} else {
+			tv = time.Unix(int64(whole), int64(math.Round(frac*1e9))).UTC()
+		}
 	}
+
 	if tv.IsZero() {
-		return time.Time{}, ErrFieldUnexpectedValue
+		return time.Time{}, newFieldError(key, "time", val, ErrFieldUnexpectedValue, errors.New("zero time"))
 	}
 	return tv, nil
 }
 
 func (ev *Event) JSONField(key EventFieldKey, dst interface{}) error {
-	val, err := ev.Field(key)
+	return bareFieldError(ev.Detailed().JSONField(key, dst))
+}
+
+func (ev DetailedEvent) JSONField(key EventFieldKey, dst interface{}) error {
+	val, err := ev.rawField(key)
 	if err != nil {
 		return err
 	}
 
+	if raw, ok := val.(json.RawMessage); ok {
+		if isNullJSON(raw) {
+			raw = json.RawMessage("null")
+		}
+		if err := json.Unmarshal(raw, dst); err != nil {
+			return newFieldError(key, fmt.Sprintf("%T", dst), val, ErrFieldUnexpectedValue, err)
+		}
+		return nil
+	}
+
 	//NOTE(bcwaldon): must marshal here to get from interface{} to something
 	// we can try unmarshalling again into the provided dst
 	bval, err := json.Marshal(val)
@@ -95,12 +382,105 @@ func (ev *Event) JSONField(key EventFieldKey, dst interface{}) error {
 	}
 
 	if err := json.Unmarshal(bval, dst); err != nil {
-		return ErrFieldUnexpectedValue
+		return newFieldError(key, fmt.Sprintf("%T", dst), val, ErrFieldUnexpectedValue, err)
 	}
 
 	return nil
 }
 
+func (ev *Event) StringSliceField(key EventFieldKey) ([]string, error) {
+	val, err := ev.Detailed().StringSliceField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) StringSliceField(key EventFieldKey) ([]string, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if sv, ok := val.([]string); ok {
+		return sv, nil
+	}
+
+	items, ok := val.([]interface{})
+	if !ok {
+		return nil, newFieldError(key, "[]string", val, ErrFieldIncorrectType, nil)
+	}
+
+	sv := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, newFieldError(key, "[]string", val, ErrFieldIncorrectType, nil)
+		}
+		sv[i] = s
+	}
+	return sv, nil
+}
+
+func (ev *Event) Int64SliceField(key EventFieldKey) ([]int64, error) {
+	val, err := ev.Detailed().Int64SliceField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) Int64SliceField(key EventFieldKey) ([]int64, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if iv, ok := val.([]int64); ok {
+		return iv, nil
+	}
+
+	items, ok := val.([]interface{})
+	if !ok {
+		return nil, newFieldError(key, "[]int64", val, ErrFieldIncorrectType, nil)
+	}
+
+	iv := make([]int64, len(items))
+	for i, item := range items {
+		n, err := toInt64(item)
+		if err != nil {
+			return nil, newFieldError(key, "[]int64", val, err, nil)
+		}
+		iv[i] = n
+	}
+	return iv, nil
+}
+
+func (ev *Event) FloatSliceField(key EventFieldKey) ([]float64, error) {
+	val, err := ev.Detailed().FloatSliceField(key)
+	return val, bareFieldError(err)
+}
+
+func (ev DetailedEvent) FloatSliceField(key EventFieldKey) ([]float64, error) {
+	val, err := ev.Field(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if fv, ok := val.([]float64); ok {
+		return fv, nil
+	}
+
+	items, ok := val.([]interface{})
+	if !ok {
+		return nil, newFieldError(key, "[]float64", val, ErrFieldIncorrectType, nil)
+	}
+
+	fv := make([]float64, len(items))
+	for i, item := range items {
+		f, err := toFloat64(item)
+		if err != nil {
+			return nil, newFieldError(key, "[]float64", val, err, nil)
+		}
+		fv[i] = f
+	}
+	return fv, nil
+}
+
 type EventField struct {
 	Key   EventFieldKey `json:"key"`
 	Value interface{}   `json:"value"`
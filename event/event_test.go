@@ -2,6 +2,8 @@ package event
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -64,6 +66,7 @@ func TestFieldCoreTypesIncorrect(t *testing.T) {
   "fields": [
     {"key": "example_str", "value": "XYZ"},
     {"key": "example_int", "value": 3},
+    {"key": "example_bool", "value": true},
 	{"key": "example_time", "value": "2022-03-04T01:13:44.1429Z"}
   ]
 }`
@@ -80,8 +83,9 @@ func TestFieldCoreTypesIncorrect(t *testing.T) {
 		t.Errorf("received incorrect error when parsing time as int: %v", err)
 	}
 
-	if _, err := ev.TimeField(EventFieldKey("example_int")); err != ErrFieldIncorrectType {
-		t.Errorf("received incorrect error when parsing int as time: %v", err)
+	// numbers are accepted as Unix timestamps, but other types are not
+	if _, err := ev.TimeField(EventFieldKey("example_bool")); err != ErrFieldIncorrectType {
+		t.Errorf("received incorrect error when parsing bool as time: %v", err)
 	}
 }
 
@@ -148,3 +152,399 @@ func TestFieldJSONUnexpectedValue(t *testing.T) {
 		}
 	}
 }
+
+func TestFieldExtendedTypes(t *testing.T) {
+	raw := `{
+  "type": "example_type",
+  "fields": [
+    {"key": "example_bool", "value": true},
+    {"key": "example_float", "value": 12.43},
+    {"key": "example_int64", "value": 9007199254740992},
+    {"key": "example_dur_str", "value": "1m30s"},
+    {"key": "example_dur_num", "value": 1500000000},
+    {"key": "example_str_slice", "value": ["a", "b"]},
+    {"key": "example_int_slice", "value": [1, 2, 3]},
+    {"key": "example_float_slice", "value": [1.5, 2]}
+  ]
+}`
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	if got, err := ev.BoolField(EventFieldKey("example_bool")); err != nil || got != true {
+		t.Errorf("unexpected bool result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.FloatField(EventFieldKey("example_float")); err != nil || got != 12.43 {
+		t.Errorf("unexpected float result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.Int64Field(EventFieldKey("example_int64")); err != nil || got != 9007199254740992 {
+		t.Errorf("unexpected int64 result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.Uint64Field(EventFieldKey("example_int64")); err != nil || got != 9007199254740992 {
+		t.Errorf("unexpected uint64 result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.DurationField(EventFieldKey("example_dur_str")); err != nil || got != 90*time.Second {
+		t.Errorf("unexpected duration result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.DurationField(EventFieldKey("example_dur_num")); err != nil || got != 1500*time.Millisecond {
+		t.Errorf("unexpected duration result: got=%v err=%v", got, err)
+	}
+
+	gotStrs, err := ev.StringSliceField(EventFieldKey("example_str_slice"))
+	if err != nil || !reflect.DeepEqual([]string{"a", "b"}, gotStrs) {
+		t.Errorf("unexpected string slice result: got=%v err=%v", gotStrs, err)
+	}
+	gotInts, err := ev.Int64SliceField(EventFieldKey("example_int_slice"))
+	if err != nil || !reflect.DeepEqual([]int64{1, 2, 3}, gotInts) {
+		t.Errorf("unexpected int64 slice result: got=%v err=%v", gotInts, err)
+	}
+	gotFloats, err := ev.FloatSliceField(EventFieldKey("example_float_slice"))
+	if err != nil || !reflect.DeepEqual([]float64{1.5, 2}, gotFloats) {
+		t.Errorf("unexpected float slice result: got=%v err=%v", gotFloats, err)
+	}
+}
+
+func TestFieldNativeTypes(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("example_int", 7),
+		Field("example_uint8", uint8(8)),
+		Field("example_float32", float32(1.5)),
+		Field("example_dur", 3*time.Second),
+		Field("example_str_slice", []string{"x"}),
+	)
+
+	if got, err := ev.IntField(EventFieldKey("example_int")); err != nil || got != 7 {
+		t.Errorf("unexpected int result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.Int64Field(EventFieldKey("example_uint8")); err != nil || got != 8 {
+		t.Errorf("unexpected int64 result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.FloatField(EventFieldKey("example_float32")); err != nil || got != 1.5 {
+		t.Errorf("unexpected float result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.DurationField(EventFieldKey("example_dur")); err != nil || got != 3*time.Second {
+		t.Errorf("unexpected duration result: got=%v err=%v", got, err)
+	}
+	if got, err := ev.StringSliceField(EventFieldKey("example_str_slice")); err != nil || !reflect.DeepEqual([]string{"x"}, got) {
+		t.Errorf("unexpected string slice result: got=%v err=%v", got, err)
+	}
+}
+
+func TestFieldExtendedTypesIncorrect(t *testing.T) {
+	raw := `{
+  "type": "example_type",
+  "fields": [
+    {"key": "example_str", "value": "XYZ"},
+    {"key": "example_int", "value": 3},
+    {"key": "example_mixed_slice", "value": ["a", 1]}
+  ]
+}`
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	if _, err := ev.BoolField(EventFieldKey("example_int")); err != ErrFieldIncorrectType {
+		t.Errorf("received incorrect error when parsing int as bool: %v", err)
+	}
+	if _, err := ev.FloatField(EventFieldKey("example_str")); err != ErrFieldIncorrectType {
+		t.Errorf("received incorrect error when parsing str as float: %v", err)
+	}
+	if _, err := ev.Int64Field(EventFieldKey("example_str")); err != ErrFieldIncorrectType {
+		t.Errorf("received incorrect error when parsing str as int64: %v", err)
+	}
+	if _, err := ev.DurationField(EventFieldKey("example_str")); err != ErrFieldUnexpectedValue {
+		t.Errorf("received incorrect error when parsing str as duration: %v", err)
+	}
+	if _, err := ev.StringSliceField(EventFieldKey("example_str")); err != ErrFieldIncorrectType {
+		t.Errorf("received incorrect error when parsing str as slice: %v", err)
+	}
+	if _, err := ev.StringSliceField(EventFieldKey("example_mixed_slice")); err != ErrFieldIncorrectType {
+		t.Errorf("received incorrect error when parsing mixed slice: %v", err)
+	}
+	if _, err := ev.BoolField(EventFieldKey("example_missing")); err != ErrFieldMissing {
+		t.Errorf("received incorrect error when parsing missing field: %v", err)
+	}
+}
+
+func TestFieldIntUnexpectedValue(t *testing.T) {
+	raw := `{
+  "type": "example_type",
+  "fields": [
+    {"key": "example_fraction", "value": 3.7},
+    {"key": "example_overflow", "value": 1e300},
+    {"key": "example_negative", "value": -1}
+  ]
+}`
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	for _, key := range []EventFieldKey{"example_fraction", "example_overflow"} {
+		if _, err := ev.IntField(key); err != ErrFieldUnexpectedValue {
+			t.Errorf("received incorrect error when parsing %s as int: %v", key, err)
+		}
+		if _, err := ev.Int64Field(key); err != ErrFieldUnexpectedValue {
+			t.Errorf("received incorrect error when parsing %s as int64: %v", key, err)
+		}
+	}
+
+	if _, err := ev.Uint64Field(EventFieldKey("example_negative")); err != ErrFieldUnexpectedValue {
+		t.Errorf("received incorrect error when parsing negative as uint64: %v", err)
+	}
+}
+
+func TestEventMetadataRoundTrip(t *testing.T) {
+	parent := NewEvent(EventType("parent_type"))
+	want := NewEventFrom(parent, EventType("example_type"), Field("example_str", "XYZ"))
+	want.Source = "example_source"
+	want.SchemaVersion = "2"
+
+	if want.ID == "" || want.ID == parent.ID {
+		t.Errorf("expected unique event ID, got %q", want.ID)
+	}
+	if want.Time.IsZero() {
+		t.Errorf("expected event time to be set")
+	}
+	if want.CausationID != parent.ID || want.CorrelationID != parent.ID {
+		t.Errorf("unexpected causation/correlation IDs: %q/%q", want.CausationID, want.CorrelationID)
+	}
+
+	raw, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("failed marshaling JSON: %v", err)
+	}
+
+	var got Event
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(*want, got) {
+		t.Errorf("event did not survive round trip: want=%+v got=%+v", *want, got)
+	}
+}
+
+func TestEventMarshalValue(t *testing.T) {
+	ev := Event{Type: EventType("example_type"), Fields: []EventField{Field("example_str", "XYZ")}}
+
+	byPtr, err := json.Marshal(&ev)
+	if err != nil {
+		t.Fatalf("failed marshaling JSON: %v", err)
+	}
+	byVal, err := json.Marshal([]Event{ev})
+	if err != nil {
+		t.Fatalf("failed marshaling JSON: %v", err)
+	}
+
+	want := "[" + string(byPtr) + "]"
+	if string(byVal) != want {
+		t.Errorf("unexpected JSON: want=%s got=%s", want, byVal)
+	}
+}
+
+func TestEventMetadataLegacy(t *testing.T) {
+	raw := `{"type": "example_type", "fields": [{"key": "example_str", "value": "XYZ"}]}`
+
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	want := Event{
+		Type:   EventType("example_type"),
+		Fields: []EventField{Field("example_str", "XYZ")},
+	}
+	if !reflect.DeepEqual(want, ev) {
+		t.Errorf("unexpected event: want=%+v got=%+v", want, ev)
+	}
+
+	out, err := json.Marshal(&ev)
+	if err != nil {
+		t.Fatalf("failed marshaling JSON: %v", err)
+	}
+
+	wantOut := `{"type":"example_type","fields":[{"key":"example_str","value":"XYZ"}]}`
+	if string(out) != wantOut {
+		t.Errorf("unexpected JSON: want=%s got=%s", wantOut, out)
+	}
+}
+
+func TestFieldError(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("example_int", 3.0),
+		Field("example_time", "yesterday"),
+	)
+
+	if _, err := ev.StringField(EventFieldKey("example_int")); err != ErrFieldIncorrectType {
+		t.Errorf("expected bare ErrFieldIncorrectType, got %v", err)
+	}
+
+	_, err := ev.Detailed().StringField(EventFieldKey("example_int"))
+
+	var ferr *FieldError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected *FieldError, got %T", err)
+	}
+	if ferr.Key != "example_int" || ferr.Want != "string" || ferr.Got != "float64" {
+		t.Errorf("unexpected field error: %+v", ferr)
+	}
+
+	wantMsg := "event field is of incorrect type: key=example_int want=string got=float64"
+	if err.Error() != wantMsg {
+		t.Errorf("unexpected error message: want=%q got=%q", wantMsg, err.Error())
+	}
+
+	_, err = ev.Detailed().TimeField(EventFieldKey("example_time"))
+	if !errors.Is(err, ErrFieldUnexpectedValue) {
+		t.Errorf("expected ErrFieldUnexpectedValue, got %v", err)
+	}
+
+	var perr *time.ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("expected underlying *time.ParseError, got %v", err)
+	}
+
+	_, err = ev.Detailed().Field(EventFieldKey("example_missing"))
+	if !errors.As(err, &ferr) || ferr.Key != "example_missing" || !errors.Is(err, ErrFieldMissing) {
+		t.Errorf("unexpected missing field error: %v", err)
+	}
+}
+
+func TestFieldTimeFormats(t *testing.T) {
+	raw := `{
+  "type": "example_type",
+  "fields": [
+    {"key": "example_rfc3339", "value": "2022-03-04T01:13:44Z"},
+    {"key": "example_rfc3339nano", "value": "2022-03-04T01:13:44.1429Z"},
+    {"key": "example_date", "value": "2022-03-04"},
+    {"key": "example_epoch_s", "value": 1646356424},
+    {"key": "example_epoch_s_frac", "value": 1646356424.5},
+    {"key": "example_epoch_ms", "value": 1646356424142},
+    {"key": "example_custom", "value": "04/03/2022"}
+  ]
+}`
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	tests := []struct {
+		key  EventFieldKey
+		want time.Time
+	}{
+		{"example_rfc3339", time.Date(2022, time.March, 4, 1, 13, 44, 0, time.UTC)},
+		{"example_rfc3339nano", time.Date(2022, time.March, 4, 1, 13, 44, 142900000, time.UTC)},
+		{"example_date", time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"example_epoch_s", time.Date(2022, time.March, 4, 1, 13, 44, 0, time.UTC)},
+		{"example_epoch_s_frac", time.Date(2022, time.March, 4, 1, 13, 44, 500000000, time.UTC)},
+		{"example_epoch_ms", time.Date(2022, time.March, 4, 1, 13, 44, 142000000, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ev.TimeField(tt.key)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.key, err)
+			continue
+		}
+		if !tt.want.Equal(got) {
+			t.Errorf("%s: received incorrect time value: want=%v got=%v", tt.key, tt.want, got)
+		}
+	}
+
+	if _, err := ev.TimeField(EventFieldKey("example_custom")); !errors.Is(err, ErrFieldUnexpectedValue) {
+		t.Errorf("expected custom layout to be rejected by default, got %v", err)
+	}
+
+	got, err := ev.TimeFieldWithLayout(EventFieldKey("example_custom"), "02/01/2006")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC); !want.Equal(got) {
+		t.Errorf("received incorrect time value: want=%v got=%v", want, got)
+	}
+}
+
+func largeEvent(n int) *Event {
+	fields := make([]EventField, n)
+	for i := range fields {
+		fields[i] = Field(EventFieldKey(fmt.Sprintf("field_%d", i)), float64(i))
+	}
+	return NewEvent(EventType("example_type"), fields...)
+}
+
+func TestFieldIndex(t *testing.T) {
+	ev := largeEvent(100)
+
+	for _, i := range []int{0, 50, 99} {
+		if got, err := ev.IntField(EventFieldKey(fmt.Sprintf("field_%d", i))); err != nil || got != i {
+			t.Errorf("unexpected indexed lookup: want=%d got=%v err=%v", i, got, err)
+		}
+	}
+
+	// removing a field shifts every later position
+	ev.DeleteField("field_10")
+	if got, err := ev.IntField("field_50"); err != nil || got != 50 {
+		t.Errorf("unexpected lookup after delete: got=%v err=%v", got, err)
+	}
+	if ev.HasField("field_10") {
+		t.Errorf("deleted field still found")
+	}
+
+	// direct appends are detected without calling a mutation helper
+	ev.Fields = append(ev.Fields, Field("appended", 1.0))
+	if got, err := ev.IntField("appended"); err != nil || got != 1 {
+		t.Errorf("unexpected lookup after append: got=%v err=%v", got, err)
+	}
+
+	// misses are answered by the index alone
+	if ev.HasField("field_1000") {
+		t.Errorf("unexpected field found")
+	}
+
+	// in-place key changes never yield a stale position for the old key
+	ev.Fields[0].Key = "renamed"
+	if ev.HasField("field_0") {
+		t.Errorf("renamed field still found under old key")
+	}
+
+	// copies share the index holder but rebuild for their own fields
+	cp := *ev
+	cp.Fields = append([]EventField(nil), ev.Fields[1:]...)
+	if got, err := cp.IntField("field_1"); err != nil || got != 1 {
+		t.Errorf("unexpected lookup in copy: got=%v err=%v", got, err)
+	}
+	if got, err := ev.IntField("field_1"); err != nil || got != 1 {
+		t.Errorf("unexpected lookup after copy: got=%v err=%v", got, err)
+	}
+}
+
+func BenchmarkEventField(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		ev := largeEvent(n)
+		keys := make([]EventFieldKey, n)
+		for i := range keys {
+			keys[i] = EventFieldKey(fmt.Sprintf("field_%d", i))
+		}
+
+		b.Run(fmt.Sprintf("fields=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ev.FloatField(keys[i%n]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("fields=%d/miss", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if ev.HasField("missing") {
+					b.Fatal("unexpected field found")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package event
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Predicate reports whether an event should be passed to a handler, see
+// WithPredicate.
+type Predicate func(*Event) bool
+
+// ParseFilter compiles a filter expression into a Predicate. Expressions
+// compare field values with literals or other fields, e.g.
+//
+//	region == "EU" && (priority > 5 || urgent)
+//
+// Supported operators are ==, !=, <, <=, >, >=, &&, || and !, and operands
+// are field keys (including nested paths, see path.go), double-quoted
+// strings, numbers, true, false and null. A bare field key is true only
+// for a boolean field set to true. Comparisons involving a missing field
+// are always false, as are ordering comparisons between values that are
+// not both numbers or both strings.
+func ParseFilter(expr string) (Predicate, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := filterParser{toks: toks}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d in filter", tok.text, tok.pos)
+	}
+	return Predicate(node), nil
+}
+
+// MustParseFilter is like ParseFilter but panics if the expression is
+// invalid. It is intended for filters that are constant in source code.
+func MustParseFilter(expr string) Predicate {
+	p, err := ParseFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+type filterTokenKind int
+
+const (
+	tokEOF filterTokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+var filterOps = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"}
+
+func isFilterIdentRune(r rune, first bool) bool {
+	if unicode.IsLetter(r) || r == '_' {
+		return true
+	}
+	return !first && (unicode.IsDigit(r) || r == '.' || r == '[' || r == ']' || r == '-')
+}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var toks []filterToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, filterToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			toks = append(toks, filterToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at offset %d in filter", i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("malformed string at offset %d in filter: %v", i, err)
+			}
+			toks = append(toks, filterToken{kind: tokString, text: s, pos: i})
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(expr) && strings.IndexByte("0123456789.eE+-", expr[end]) >= 0 {
+				end++
+			}
+			toks = append(toks, filterToken{kind: tokNumber, text: expr[i:end], pos: i})
+			i = end
+		case isFilterIdentRune(rune(c), true):
+			end := i + 1
+			for end < len(expr) && isFilterIdentRune(rune(expr[end]), false) {
+				end++
+			}
+			toks = append(toks, filterToken{kind: tokIdent, text: expr[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range filterOps {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d in filter", c, i)
+			}
+			toks = append(toks, filterToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(toks, filterToken{kind: tokEOF, pos: len(expr)}), nil
+}
+
+type filterParser struct {
+	toks []filterToken
+	pos  int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.toks[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) acceptOp(op string) bool {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(ev *Event) bool { return l(ev) || right(ev) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(ev *Event) bool { return l(ev) && right(ev) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Predicate, error) {
+	if p.acceptOp("!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ev *Event) bool { return !inner(ev) }, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at offset %d in filter", tok.pos)
+		}
+		return inner, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if tok.kind != tokOp {
+			break
+		}
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		op := tok.text
+		return func(ev *Event) bool {
+			lv, ok := left(ev)
+			if !ok {
+				return false
+			}
+			rv, ok := right(ev)
+			if !ok {
+				return false
+			}
+			return compareFilterValues(op, lv, rv)
+		}, nil
+	}
+
+	return func(ev *Event) bool {
+		v, ok := left(ev)
+		b, isBool := v.(bool)
+		return ok && isBool && b
+	}, nil
+}
+
+// filterOperand yields an operand's value, reporting false if it names a
+// missing field.
+type filterOperand func(*Event) (interface{}, bool)
+
+func (p *filterParser) parseOperand() (filterOperand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return literalOperand(tok.text), nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed number %q at offset %d in filter", tok.text, tok.pos)
+		}
+		return literalOperand(f), nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literalOperand(true), nil
+		case "false":
+			return literalOperand(false), nil
+		case "null":
+			return literalOperand(nil), nil
+		}
+		key := EventFieldKey(tok.text)
+		return func(ev *Event) (interface{}, bool) {
+			val, err := ev.Field(key)
+			return val, err == nil
+		}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d in filter", tok.text, tok.pos)
+}
+
+func literalOperand(val interface{}) filterOperand {
+	return func(*Event) (interface{}, bool) { return val, true }
+}
+
+func compareFilterValues(op string, lv, rv interface{}) bool {
+	if lf, err := toFloat64(lv); err == nil {
+		if rf, err := toFloat64(rv); err == nil {
+			return compareOrdered(op, lf < rf, lf == rf)
+		}
+	}
+	if ls, ok := lv.(string); ok {
+		if rs, ok := rv.(string); ok {
+			return compareOrdered(op, ls < rs, ls == rs)
+		}
+	}
+
+	switch op {
+	case "==":
+		return filterEqual(lv, rv)
+	case "!=":
+		return !filterEqual(lv, rv)
+	}
+	return false
+}
+
+func compareOrdered(op string, less, equal bool) bool {
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+func filterEqual(lv, rv interface{}) bool {
+	switch l := lv.(type) {
+	case nil:
+		return rv == nil
+	case bool:
+		r, ok := rv.(bool)
+		return ok && l == r
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package event
+
+import (
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("region", "EU"),
+		Field("priority", 7),
+		Field("ratio", 0.5),
+		Field("urgent", true),
+		Field("draft", false),
+		Field("owner", nil),
+		Field("location", map[string]interface{}{"country": "FR"}),
+		Field("asset-id", "a1"),
+	)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`region == "EU"`, true},
+		{`region != "EU"`, false},
+		{`region == "US"`, false},
+		{`priority > 5`, true},
+		{`priority >= 7`, true},
+		{`priority < 7`, false},
+		{`priority <= 7.0`, true},
+		{`ratio > -1`, true},
+		{`ratio < 1e-1`, false},
+		{`urgent`, true},
+		{`draft`, false},
+		{`!draft`, true},
+		{`region`, false},
+		{`urgent == true`, true},
+		{`owner == null`, true},
+		{`owner != null`, false},
+		{`location.country == "FR"`, true},
+		{`asset-id == "a1"`, true},
+		{`region == "EU" && priority > 5`, true},
+		{`region == "US" || priority > 5`, true},
+		{`region == "US" || priority > 9`, false},
+		{`!(region == "US" || priority > 9)`, true},
+		{`region == "EU" && (priority > 9 || urgent)`, true},
+		{`region == "EU" && priority > 9 || urgent`, true},
+		{`missing == "x"`, false},
+		{`missing != "x"`, false},
+		{`!(missing == "x")`, true},
+		{`region > 5`, false},
+		{`region == 5`, false},
+		{`region != 5`, true},
+		{`region < "US"`, true},
+		{`"EU" == region`, true},
+		{`priority == ratio`, false},
+		{`region == "E\"U"`, false},
+	}
+
+	for _, tt := range tests {
+		p, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("expr=%s: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got := p(ev); got != tt.want {
+			t.Errorf("expr=%s: want=%v got=%v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`region ==`,
+		`region == "EU`,
+		`(region == "EU"`,
+		`region == "EU")`,
+		`region = "EU"`,
+		`region == "EU" &&`,
+		`priority > 5 6`,
+		`priority > -`,
+		`region == $x`,
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("expr=%s: expected error", expr)
+		}
+	}
+}
+
+func TestMustParseFilterPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	MustParseFilter(`region ==`)
+}
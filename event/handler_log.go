@@ -1,25 +1,97 @@
 package event
 
 import (
-	"fmt"
+	"context"
+	"sync/atomic"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-type logEventPublisher struct {
+type LogHandlerConfig struct {
+	// Level defaults to zapcore.InfoLevel
+	Level zapcore.Level
+	// Message defaults to "event handled"
+	Message string
+	// FieldPrefix is prepended to each event field key, defaulting to
+	// "event_field_"
+	FieldPrefix string
+
+	// Types limits logging to the given event types, which may be
+	// patterns as accepted by MatchEventType; nil logs all events
+	Types []EventType
+	// SampleEvery logs only one in every N events; zero or one logs all
+	SampleEvery int
+
+	// Redactor masks sensitive field values. Values are always masked
+	// rather than stripped so the presence of a field remains visible.
+	Redactor *Redactor
+}
+
+// NewLogHandler logs each event it receives, e.g. to mirror the events
+// passing through an EventRouter during development.
+func NewLogHandler(logger *zap.Logger, cfg LogHandlerConfig) EventHandler {
+	if cfg.Message == "" {
+		cfg.Message = "event handled"
+	}
+	if cfg.FieldPrefix == "" {
+		cfg.FieldPrefix = "event_field_"
+	}
+	return &logHandler{logger: logger, cfg: cfg}
+}
+
+type logHandler struct {
 	logger *zap.Logger
+	cfg    LogHandlerConfig
+
+	seen uint64
 }
 
-func (e *logEventPublisher) HandleEvent(ev *Event) error {
-	zfs := make([]zap.Field, len(ev.Fields)+1)
-	for i, ef := range ev.Fields {
-		zfs[i] = zap.Reflect(fmt.Sprintf("event_field_%s", ef.Key), ef.Value)
+func (h *logHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	if !h.matches(ev.Type) {
+		return nil
+	}
+
+	if n := uint64(h.cfg.SampleEvery); n > 1 && (atomic.AddUint64(&h.seen, 1)-1)%n != 0 {
+		return nil
 	}
-	zfs[len(zfs)-1] = zap.String("event_type", string(ev.Type))
-	e.logger.Info("event published", zfs...)
+
+	ce := h.logger.Check(h.cfg.Level, h.cfg.Message)
+	if ce == nil {
+		return nil
+	}
+
+	zfs := make([]zap.Field, 0, len(ev.Fields)+3)
+	zfs = append(zfs,
+		zap.String("event_type", string(ev.Type)),
+		zap.String("event_id", ev.ID),
+	)
+	if ev.Source != "" {
+		zfs = append(zfs, zap.String("event_source", ev.Source))
+	}
+	for _, ef := range ev.Fields {
+		val := ef.Value
+		if h.cfg.Redactor.IsSensitive(ev.Type, ef.Key) || h.cfg.Redactor.hasNested(ef.Key) {
+			val, _ = h.cfg.Redactor.redactField(ev.Type, Field(ef.Key, deepCopyValue(val)), RedactMask)
+		}
+		zfs = append(zfs, zap.Reflect(h.cfg.FieldPrefix+string(ef.Key), val))
+	}
+	ce.Write(zfs...)
 	return nil
 }
 
-func (e *logEventPublisher) Handles() []EventType {
-	return nil
+func (h *logHandler) matches(typ EventType) bool {
+	if h.cfg.Types == nil {
+		return true
+	}
+	for _, t := range h.cfg.Types {
+		if MatchEventType(t, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *logHandler) Handles() []EventType {
+	return h.cfg.Types
 }
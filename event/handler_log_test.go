@@ -0,0 +1,114 @@
+package event
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogHandler(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	eh := NewLogHandler(zap.New(core), LogHandlerConfig{
+		Level:       zap.DebugLevel,
+		Message:     "outbound event",
+		FieldPrefix: "f_",
+		Redactor:    &Redactor{Keys: []EventFieldKey{"address", "location.lat"}},
+	})
+
+	ev := &Event{
+		ID:   "abc-123",
+		Type: EventType("asset_created"),
+		Fields: []EventField{
+			Field("name", "XYZ"),
+			Field("address", "1 Main St"),
+			Field("location", map[string]interface{}{"lat": 1.5}),
+		},
+	}
+	if err := eh.HandleEvent(context.Background(), ev); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	if entries[0].Level != zapcore.DebugLevel || entries[0].Message != "outbound event" {
+		t.Errorf("unexpected entry: level=%v message=%q", entries[0].Level, entries[0].Message)
+	}
+
+	fields := entries[0].ContextMap()
+	if fields["event_type"] != "asset_created" || fields["event_id"] != "abc-123" {
+		t.Errorf("unexpected envelope fields: %v", fields)
+	}
+	if fields["f_name"] != "XYZ" {
+		t.Errorf("unexpected name: %v", fields["f_name"])
+	}
+	if fields["f_address"] != RedactedValue {
+		t.Errorf("address not masked: %v", fields["f_address"])
+	}
+	if want := map[string]interface{}{"lat": RedactedValue}; !reflect.DeepEqual(want, fields["f_location"]) {
+		t.Errorf("nested value not masked: want=%v got=%v", want, fields["f_location"])
+	}
+	if lat, err := ev.FloatField("location.lat"); err != nil || lat != 1.5 {
+		t.Errorf("logged event modified: got=%v err=%v", lat, err)
+	}
+}
+
+func TestLogHandlerTypes(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	eh := NewLogHandler(zap.New(core), LogHandlerConfig{Types: []EventType{"test1"}})
+
+	if got := eh.Handles(); len(got) != 1 || got[0] != "test1" {
+		t.Errorf("unexpected Handles: %v", got)
+	}
+
+	eh.HandleEvent(context.Background(), &Event{Type: EventType("test1")})
+	eh.HandleEvent(context.Background(), &Event{Type: EventType("test2")})
+
+	if logs.Len() != 1 {
+		t.Errorf("expected 1 log entry, got %d", logs.Len())
+	}
+}
+
+func TestLogHandlerTypePattern(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	eh := NewLogHandler(zap.New(core), LogHandlerConfig{Types: []EventType{"dataset.*"}})
+
+	er := NewEventRouter(zap.NewNop())
+	er.Mount(eh)
+
+	er.HandleEvent(context.Background(), &Event{Type: EventType("dataset.created")})
+	er.HandleEvent(context.Background(), &Event{Type: EventType("asset.created")})
+
+	if logs.Len() != 1 {
+		t.Errorf("expected 1 log entry, got %d", logs.Len())
+	}
+}
+
+func TestLogHandlerSampling(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	eh := NewLogHandler(zap.New(core), LogHandlerConfig{SampleEvery: 3})
+
+	for i := 0; i < 7; i++ {
+		eh.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+	}
+
+	if logs.Len() != 3 {
+		t.Errorf("expected 3 log entries, got %d", logs.Len())
+	}
+}
+
+func TestLogHandlerLevelDisabled(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	eh := NewLogHandler(zap.New(core), LogHandlerConfig{Level: zap.DebugLevel})
+
+	eh.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+	if logs.Len() != 0 {
+		t.Errorf("expected no log entries, got %d", logs.Len())
+	}
+}
@@ -0,0 +1,213 @@
+package event
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// IdempotencyStore remembers which events have been processed, see
+// Idempotent.
+type IdempotencyStore interface {
+	// Claim records key for ttl, reporting false if it was already
+	// recorded and has not yet expired. Claims must be atomic so that
+	// concurrent duplicates are only processed once.
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release forgets key so that the event may be processed again
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyKeyFunc derives the key identifying duplicates of ev. An
+// empty key disables duplicate suppression for the event.
+type IdempotencyKeyFunc func(ev *Event) (string, error)
+
+// KeyByID identifies duplicates by event ID.
+func KeyByID(ev *Event) (string, error) {
+	return ev.ID, nil
+}
+
+// KeyByField identifies duplicates by the value of a single field.
+func KeyByField(key EventFieldKey) IdempotencyKeyFunc {
+	return KeyByFieldsHash(key)
+}
+
+// KeyByFieldsHash identifies duplicates by a hash of the event type and
+// the values of the given fields, e.g. for producers that assign a new ID
+// on every redelivery.
+func KeyByFieldsHash(keys ...EventFieldKey) IdempotencyKeyFunc {
+	return func(ev *Event) (string, error) {
+		h := sha256.New()
+		h.Write([]byte(ev.Type))
+		for _, key := range keys {
+			val, err := ev.Detailed().Field(key)
+			if err != nil {
+				return "", err
+			}
+			data, err := json.Marshal(val)
+			if err != nil {
+				return "", err
+			}
+			h.Write([]byte{0})
+			h.Write(data)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+}
+
+type IdempotencyConfig struct {
+	// Store defaults to an in-memory store with default capacity
+	Store IdempotencyStore
+	// TTL defaults to 24 hours
+	TTL time.Duration
+	// Key defaults to KeyByID
+	Key IdempotencyKeyFunc
+	// Scope prefixes every key, in place of the route or handler name
+	Scope string
+
+	Logger *zap.Logger
+}
+
+// Idempotent skips events that the wrapped handler has already processed
+// successfully, returning nil for them so that transports acknowledge the
+// duplicate delivery. Keys are scoped to Scope or, by default, to the
+// route name when dispatched by an EventRouter and to the handler name
+// otherwise, so a store may be shared by several handlers and by every
+// replica of a service. Handlers of the same type sharing a store should
+// be mounted WithName. If the handler fails its claim on the event is
+// released, allowing a redelivery to be processed.
+func Idempotent(cfg IdempotencyConfig) Middleware {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryIdempotencyStore(0)
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.Key == nil {
+		cfg.Key = KeyByID
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	return func(next EventHandler) EventHandler {
+		name := handlerName(next)
+		return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+			scope := cfg.Scope
+			if scope == "" {
+				scope = name
+				if rt := RouteFromContext(ctx); rt != nil {
+					scope = rt.Name()
+				}
+			}
+
+			key, err := cfg.Key(ev)
+			if err != nil {
+				return err
+			}
+			if key == "" {
+				return next.HandleEvent(ctx, ev)
+			}
+			key = scope + "\x00" + key
+
+			ok, err := cfg.Store.Claim(ctx, key, cfg.TTL)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				cfg.Logger.Debug("skipped duplicate event",
+					zap.String("handler", scope),
+					zap.String("type", string(ev.Type)),
+					zap.String("event_id", ev.ID),
+				)
+				return nil
+			}
+
+			err = next.HandleEvent(ctx, ev)
+			if isHandlerFailure(err) {
+				if rerr := cfg.Store.Release(ctx, key); rerr != nil {
+					cfg.Logger.Error("failed releasing idempotency key", zap.String("handler", scope), zap.Error(rerr))
+				}
+			}
+			return err
+		})
+	}
+}
+
+// NewMemoryIdempotencyStore returns a store holding at most capacity keys,
+// evicting the least recently used beyond that. A capacity of zero or less
+// selects a default of 10000.
+func NewMemoryIdempotencyStore(capacity int) *MemoryIdempotencyStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &MemoryIdempotencyStore{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		now:      time.Now,
+	}
+}
+
+type MemoryIdempotencyStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+
+	now func() time.Time
+}
+
+type idempotencyEntry struct {
+	key     string
+	expires time.Time
+}
+
+func (s *MemoryIdempotencyStore) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*idempotencyEntry)
+		if now.Before(entry.expires) {
+			s.lru.MoveToFront(el)
+			return false, nil
+		}
+		entry.expires = now.Add(ttl)
+		s.lru.MoveToFront(el)
+		return true, nil
+	}
+
+	s.entries[key] = s.lru.PushFront(&idempotencyEntry{key: key, expires: now.Add(ttl)})
+	for s.lru.Len() > s.capacity {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*idempotencyEntry).key)
+	}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.lru.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of keys held, including any that have expired
+// but not yet been evicted.
+func (s *MemoryIdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
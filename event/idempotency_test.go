@@ -0,0 +1,220 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestIdempotent(t *testing.T) {
+	eh := &fixtureHandler{}
+	ih := Idempotent(IdempotencyConfig{})(eh)
+
+	ev1 := &Event{ID: "a", Type: EventType("test")}
+	ev2 := &Event{ID: "b", Type: EventType("test")}
+
+	for _, ev := range []*Event{ev1, ev1, ev2, ev1} {
+		if err := ih.HandleEvent(context.Background(), ev); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if len(eh.events) != 2 || eh.events[0].ID != "a" || eh.events[1].ID != "b" {
+		t.Errorf("unexpected events: %+v", eh.events)
+	}
+}
+
+func TestIdempotentReleasesOnFailure(t *testing.T) {
+	errTransient := errors.New("transient")
+	eh := &flakyHandler{failures: 1, err: errTransient}
+	ih := Idempotent(IdempotencyConfig{})(eh)
+
+	ev := &Event{ID: "a", Type: EventType("test")}
+	if err := ih.HandleEvent(context.Background(), ev); err != errTransient {
+		t.Errorf("expected handler error, got %v", err)
+	}
+	if err := ih.HandleEvent(context.Background(), ev); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ih.HandleEvent(context.Background(), ev); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if eh.calls != 2 {
+		t.Errorf("expected redelivery after failure to be processed once, got %d calls", eh.calls)
+	}
+}
+
+func TestIdempotentScopedByHandler(t *testing.T) {
+	store := NewMemoryIdempotencyStore(0)
+	eh1 := &fixtureHandler{}
+	eh2 := &slowHandler{name: "other", active: new(int32), maxSeen: new(int32)}
+
+	ev := &Event{ID: "a", Type: EventType("test")}
+	Idempotent(IdempotencyConfig{Store: store})(eh1).HandleEvent(context.Background(), ev)
+	Idempotent(IdempotencyConfig{Store: store})(eh2).HandleEvent(context.Background(), ev)
+
+	if store.Len() != 2 {
+		t.Errorf("expected a key per handler, got %d", store.Len())
+	}
+}
+
+func TestIdempotentScopedByRoute(t *testing.T) {
+	store := NewMemoryIdempotencyStore(0)
+	eh1 := &fixtureHandler{}
+	eh2 := &fixtureHandler{}
+
+	er := NewEventRouter(zap.NewNop())
+	er.Use(Idempotent(IdempotencyConfig{Store: store}))
+	er.Mount(eh1, WithName("first"))
+	er.Mount(eh2, WithName("second"))
+
+	ev := &Event{ID: "a", Type: EventType("test")}
+	er.HandleEvent(context.Background(), ev)
+	er.HandleEvent(context.Background(), ev)
+
+	// handlers of the same type each see the event exactly once
+	if len(eh1.events) != 1 || len(eh2.events) != 1 {
+		t.Errorf("unexpected events: eh1=%d eh2=%d", len(eh1.events), len(eh2.events))
+	}
+	if store.Len() != 2 {
+		t.Errorf("expected a key per route, got %d", store.Len())
+	}
+
+	// keys don't depend on mount order, so another replica sharing the
+	// store skips the event too
+	eh3 := &fixtureHandler{}
+	eh4 := &fixtureHandler{}
+	replica := NewEventRouter(zap.NewNop())
+	replica.Use(Idempotent(IdempotencyConfig{Store: store}))
+	replica.Mount(eh3, WithName("second"))
+	replica.Mount(eh4, WithName("first"))
+
+	replica.HandleEvent(context.Background(), ev)
+	if len(eh3.events) != 0 || len(eh4.events) != 0 {
+		t.Errorf("unexpected events: eh3=%d eh4=%d", len(eh3.events), len(eh4.events))
+	}
+}
+
+func TestIdempotentScope(t *testing.T) {
+	store := NewMemoryIdempotencyStore(0)
+	eh := &fixtureHandler{}
+	h := Idempotent(IdempotencyConfig{Store: store, Scope: "orders"})(eh)
+
+	h.HandleEvent(context.Background(), &Event{ID: "a"})
+
+	if _, ok := store.entries["orders\x00a"]; !ok || store.Len() != 1 {
+		t.Errorf("expected scoped key, got %v", store.entries)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	ev1 := NewEvent(EventType("test"), Field("order", "o-1"), Field("line", 1))
+	ev2 := NewEvent(EventType("test"), Field("order", "o-1"), Field("line", 2))
+	ev3 := NewEvent(EventType("other"), Field("order", "o-1"), Field("line", 1))
+
+	byOrder := KeyByField("order")
+	k1, _ := byOrder(ev1)
+	k2, _ := byOrder(ev2)
+	if k1 == "" || k1 != k2 {
+		t.Errorf("expected equal keys for equal field values: %q %q", k1, k2)
+	}
+
+	byLine := KeyByFieldsHash("order", "line")
+	k1, _ = byLine(ev1)
+	k2, _ = byLine(ev2)
+	k3, _ := byLine(ev3)
+	if k1 == k2 || k1 == k3 {
+		t.Errorf("expected distinct keys: %q %q %q", k1, k2, k3)
+	}
+
+	if _, err := KeyByField("missing")(ev1); !errors.Is(err, ErrFieldMissing) {
+		t.Errorf("expected ErrFieldMissing, got %v", err)
+	}
+
+	if k, _ := KeyByID(ev1); k != ev1.ID {
+		t.Errorf("unexpected key: %q", k)
+	}
+}
+
+func TestIdempotentEmptyKey(t *testing.T) {
+	eh := &fixtureHandler{}
+	ih := Idempotent(IdempotencyConfig{})(eh)
+
+	ev := &Event{Type: EventType("test")}
+	ih.HandleEvent(context.Background(), ev)
+	ih.HandleEvent(context.Background(), ev)
+
+	if len(eh.events) != 2 {
+		t.Errorf("expected events without a key to always be handled, got %d", len(eh.events))
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, time.March, 4, 0, 0, 0, 0, time.UTC)
+
+	s := NewMemoryIdempotencyStore(2)
+	s.now = func() time.Time { return now }
+
+	claim := func(key string) bool {
+		ok, err := s.Claim(ctx, key, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return ok
+	}
+
+	if !claim("a") || claim("a") {
+		t.Errorf("expected only the first claim to succeed")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !claim("a") {
+		t.Errorf("expected expired key to be claimable")
+	}
+
+	// "a" is most recently used, so "b" is evicted by "c"
+	claim("b")
+	claim("a")
+	claim("c")
+	if s.Len() != 2 {
+		t.Errorf("unexpected store size: %d", s.Len())
+	}
+	if !claim("b") {
+		t.Errorf("expected evicted key to be claimable")
+	}
+
+	s.Release(ctx, "b")
+	if !claim("b") {
+		t.Errorf("expected released key to be claimable")
+	}
+}
+
+func TestMemoryIdempotencyStoreConcurrent(t *testing.T) {
+	s := NewMemoryIdempotencyStore(0)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.Claim(context.Background(), "a", time.Minute); ok {
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claimed != 1 {
+		t.Errorf("expected exactly one claim, got %d", claimed)
+	}
+}
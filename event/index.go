@@ -0,0 +1,89 @@
+package event
+
+import "sync/atomic"
+
+// Events with many fields are served from a key index rather than by
+// scanning Fields on every lookup. The index is built on first use and
+// rebuilt whenever the Fields slice is reallocated or resized, or after
+// any of the Event mutation helpers is used. Renaming a key by writing
+// Fields[i].Key directly is not detected: a stale position is never
+// reported for the old key, but the new key may go unfound until the next
+// mutation, so use DeleteField and SetField instead.
+//
+// The index lives in a holder installed by NewEvent, the decoders in this
+// package, Clone and the mutation helpers when an event has enough fields;
+// events built as struct literals are simply scanned. Lookups only ever
+// write to the holder, never to the Event itself, so events may be read
+// (and copied) from multiple goroutines concurrently, e.g. under a parallel
+// EventRouter.
+
+// fieldIndexThreshold is the number of fields below which a linear scan
+// is cheaper than maintaining an index.
+const fieldIndexThreshold = 16
+
+type fieldIndex struct {
+	base *EventField
+	n    int
+	pos  map[EventFieldKey]int
+}
+
+func (idx *fieldIndex) valid(fields []EventField) bool {
+	return idx != nil && idx.n == len(fields) && len(fields) > 0 && idx.base == &fields[0]
+}
+
+func newFieldIndex(fields []EventField) *fieldIndex {
+	idx := fieldIndex{
+		base: &fields[0],
+		n:    len(fields),
+		pos:  make(map[EventFieldKey]int, len(fields)),
+	}
+	for i, ef := range fields {
+		// the first occurrence of a key wins, matching Field
+		if _, ok := idx.pos[ef.Key]; !ok {
+			idx.pos[ef.Key] = i
+		}
+	}
+	return &idx
+}
+
+// fieldIndexHolder caches the *fieldIndex of an event. Events refer to it
+// by pointer so that copying an Event never copies the atomic.Value.
+type fieldIndexHolder struct {
+	v atomic.Value
+}
+
+// indexOf looks up the position of key within ev.Fields. The final result
+// reports whether the index could be used at all; if it could, a miss is
+// definitive and the caller need not scan.
+func (ev *Event) indexOf(key EventFieldKey) (int, bool, bool) {
+	if ev.index == nil || len(ev.Fields) < fieldIndexThreshold {
+		return 0, false, false
+	}
+
+	idx, _ := ev.index.v.Load().(*fieldIndex)
+	if !idx.valid(ev.Fields) {
+		idx = newFieldIndex(ev.Fields)
+		ev.index.v.Store(idx)
+	}
+
+	i, ok := idx.pos[key]
+	if ok && ev.Fields[i].Key != key {
+		// a key was renamed in place; rebuild rather than report a
+		// stale position
+		idx = newFieldIndex(ev.Fields)
+		ev.index.v.Store(idx)
+		i, ok = idx.pos[key]
+	}
+	return i, ok, true
+}
+
+// resetIndex discards any index built for ev, installing an empty holder
+// if ev has enough fields to benefit from one. It must be called whenever
+// Fields is modified or replaced by this package and, like any other
+// mutation, must not race with readers.
+func (ev *Event) resetIndex() {
+	ev.index = nil
+	if len(ev.Fields) >= fieldIndexThreshold {
+		ev.index = &fieldIndexHolder{}
+	}
+}
@@ -0,0 +1,91 @@
+package event
+
+import (
+	"encoding/json"
+)
+
+// Events may be decoded lazily, in which case each field value is kept as
+// a json.RawMessage until it is read. Field and the typed accessors decode
+// the raw value on every call, while JSONField decodes it directly into the
+// destination, and values that are never read are never decoded. Raw
+// values are written back out verbatim when the event is re-encoded as
+// JSON, so a lazily-decoded event may be forwarded cheaply.
+//
+// Code reading EventField.Value directly must be prepared to find a
+// json.RawMessage; prefer Event.Field.
+
+// LazyUnmarshaler is implemented by codecs supporting lazy decoding.
+type LazyUnmarshaler interface {
+	UnmarshalLazy([]byte, *Event) error
+}
+
+// UnmarshalLazy decodes data using codec, deferring the decoding of field
+// values if the codec supports it, and decoding eagerly otherwise.
+func UnmarshalLazy(codec Codec, data []byte, ev *Event) error {
+	if lc, ok := codec.(LazyUnmarshaler); ok {
+		return lc.UnmarshalLazy(data, ev)
+	}
+	return codec.Unmarshal(data, ev)
+}
+
+func (c *JSONCodec) UnmarshalLazy(data []byte, ev *Event) error {
+	type alias Event
+	aux := struct {
+		*alias
+		Fields []struct {
+			Key   EventFieldKey   `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"fields"`
+	}{alias: (*alias)(ev)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	ev.Fields = nil
+	if aux.Fields != nil {
+		ev.Fields = make([]EventField, len(aux.Fields))
+		for i, f := range aux.Fields {
+			ev.Fields[i] = EventField{Key: f.Key}
+			// a missing or null value decodes to nil, as it would eagerly
+			if !isNullJSON(f.Value) {
+				ev.Fields[i].Value = f.Value
+			}
+		}
+	}
+	ev.resetIndex()
+
+	return ev.Dedupe(c.DuplicateKeys)
+}
+
+func (c *compressedCodec) UnmarshalLazy(data []byte, ev *Event) error {
+	raw, err := c.comp.Decompress(data)
+	if err != nil {
+		return err
+	}
+	return UnmarshalLazy(c.codec, raw, ev)
+}
+
+// resolveValue decodes a lazily-decoded value, passing any other value
+// through untouched.
+func resolveValue(key EventFieldKey, val interface{}) (interface{}, error) {
+	raw, ok := val.(json.RawMessage)
+	if !ok {
+		return val, nil
+	}
+	if isNullJSON(raw) {
+		return nil, nil
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, newFieldError(key, "JSON", val, ErrFieldUnexpectedValue, err)
+	}
+	return out, nil
+}
+
+// isNullJSON reports whether raw is JSON null, treating an empty value as
+// null too.
+func isNullJSON(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
@@ -0,0 +1,151 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalLazy(t *testing.T) {
+	want := NewEvent(EventType("example_type"),
+		Field("example_str", "XYZ"),
+		Field("example_float", 12.43),
+		Field("example_null", nil),
+		Field("example_json", map[string]interface{}{"foo": "XYZ", "bar": []interface{}{"a"}}),
+	)
+	codec := &JSONCodec{}
+
+	data, err := codec.Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Event
+	if err := UnmarshalLazy(codec, data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := got.Fields[0].Value.(json.RawMessage); !ok {
+		t.Fatalf("expected raw field value, got %T", got.Fields[0].Value)
+	}
+
+	if val, err := got.StringField("example_str"); err != nil || val != "XYZ" {
+		t.Errorf("unexpected StringField result: val=%v err=%v", val, err)
+	}
+	if val, err := got.FloatField("example_float"); err != nil || val != 12.43 {
+		t.Errorf("unexpected FloatField result: val=%v err=%v", val, err)
+	}
+	if val, err := got.Field("example_null"); err != nil || val != nil {
+		t.Errorf("unexpected Field result: val=%v err=%v", val, err)
+	}
+	if val, err := got.StringField("example_json.bar[0]"); err != nil || val != "a" {
+		t.Errorf("unexpected nested StringField result: val=%v err=%v", val, err)
+	}
+	if _, err := got.BoolField("example_str"); !errors.Is(err, ErrFieldIncorrectType) {
+		t.Errorf("expected ErrFieldIncorrectType, got %v", err)
+	}
+
+	var msg struct{ Foo string }
+	if err := got.JSONField("example_json", &msg); err != nil || msg.Foo != "XYZ" {
+		t.Errorf("unexpected JSONField result: val=%+v err=%v", msg, err)
+	}
+
+	if !reflect.DeepEqual(want.Map(), got.Map()) {
+		t.Errorf("unexpected Map: want=%v got=%v", want.Map(), got.Map())
+	}
+
+	// raw values are re-encoded verbatim
+	out, err := codec.Marshal(&got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, out) {
+		t.Errorf("unexpected re-encoding: want=%s got=%s", data, out)
+	}
+}
+
+func TestUnmarshalLazyCompressed(t *testing.T) {
+	codec := CompressedCodec(&JSONCodec{}, &GzipCompressor{})
+
+	data, err := codec.Marshal(NewEvent(EventType("example_type"), Field("example_str", "XYZ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Event
+	if err := UnmarshalLazy(codec, data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val, err := got.StringField("example_str"); err != nil || val != "XYZ" {
+		t.Errorf("unexpected StringField result: val=%v err=%v", val, err)
+	}
+}
+
+func TestUnmarshalLazyFallback(t *testing.T) {
+	codec := &CBORCodec{}
+
+	data, err := codec.Marshal(NewEvent(EventType("example_type"), Field("example_str", "XYZ")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Event
+	if err := UnmarshalLazy(codec, data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := got.Fields[0].Value.(string); !ok {
+		t.Errorf("expected eagerly-decoded value, got %T", got.Fields[0].Value)
+	}
+}
+
+func TestLazyFieldClone(t *testing.T) {
+	ev := NewEvent(EventType("example_type"), Field("example_json", json.RawMessage(`{"foo":"XYZ"}`)))
+
+	cl := ev.Clone()
+	cl.Fields[0].Value.(json.RawMessage)[2] = 'F'
+
+	if val, err := ev.StringField("example_json.foo"); err != nil || val != "XYZ" {
+		t.Errorf("clone shares raw value: val=%v err=%v", val, err)
+	}
+}
+
+func TestLazyFieldMalformed(t *testing.T) {
+	ev := NewEvent(EventType("example_type"), Field("example_json", json.RawMessage(`{"foo":`)))
+
+	if _, err := ev.Field("example_json"); !errors.Is(err, ErrFieldUnexpectedValue) {
+		t.Errorf("expected ErrFieldUnexpectedValue, got %v", err)
+	}
+	var dst map[string]interface{}
+	if err := ev.JSONField("example_json", &dst); !errors.Is(err, ErrFieldUnexpectedValue) {
+		t.Errorf("expected ErrFieldUnexpectedValue, got %v", err)
+	}
+}
+
+func TestUnmarshalLazyMissingValue(t *testing.T) {
+	data := []byte(`{"type":"example_type","fields":[{"key":"a"},{"key":"b","value":null}]}`)
+	codec := &JSONCodec{}
+
+	var want, got Event
+	if err := codec.Unmarshal(data, &want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := UnmarshalLazy(codec, data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(want.Fields, got.Fields) {
+		t.Errorf("unexpected fields: want=%+v got=%+v", want.Fields, got.Fields)
+	}
+
+	// an empty raw value set in-process reads as null too
+	ev := NewEvent(EventType("example_type"), Field("a", json.RawMessage(nil)))
+	if val, err := ev.Field("a"); err != nil || val != nil {
+		t.Errorf("unexpected Field result: val=%v err=%v", val, err)
+	}
+	dst := &struct{}{}
+	if err := ev.JSONField("a", &dst); err != nil || dst != nil {
+		t.Errorf("unexpected JSONField result: val=%v err=%v", dst, err)
+	}
+}
@@ -0,0 +1,154 @@
+package event
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Middleware wraps an EventHandler, e.g. to add logging or recovery. See
+// EventRouter.Use.
+type Middleware func(EventHandler) EventHandler
+
+// Chain wraps eh with the given middleware, the first being outermost.
+func Chain(eh EventHandler, mws ...Middleware) EventHandler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		eh = mws[i](eh)
+	}
+	return eh
+}
+
+// WrapHandler returns a handler calling fn in place of next.HandleEvent,
+// while reporting the Handles and Name of next. It is intended for
+// implementing Middleware.
+func WrapHandler(next EventHandler, fn func(context.Context, *Event) error) EventHandler {
+	return &wrappedHandler{next: next, handle: fn}
+}
+
+type wrappedHandler struct {
+	next   EventHandler
+	handle func(context.Context, *Event) error
+}
+
+func (h *wrappedHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	return h.handle(ctx, ev)
+}
+
+func (h *wrappedHandler) Handles() []EventType {
+	return h.next.Handles()
+}
+
+func (h *wrappedHandler) Name() string {
+	return handlerName(h.next)
+}
+
+// PanicError is returned by handlers wrapped by Recover in place of a
+// panic.
+type PanicError struct {
+	Handler string
+	Value   interface{}
+	Stack   []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler %s panicked: %v", e.Handler, e.Value)
+}
+
+// Recover converts a panicking handler into one returning a *PanicError,
+// logging the panic along with its stack trace.
+func Recover(logger *zap.Logger) Middleware {
+	return func(next EventHandler) EventHandler {
+		return WrapHandler(next, func(ctx context.Context, ev *Event) (err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					perr := &PanicError{Handler: handlerName(next), Value: v, Stack: debug.Stack()}
+					logger.Error("event handler panicked",
+						zap.String("handler", perr.Handler),
+						zap.String("type", string(ev.Type)),
+						zap.Reflect("panic", v),
+						zap.ByteString("stack", perr.Stack),
+					)
+					err = perr
+				}
+			}()
+			return next.HandleEvent(ctx, ev)
+		})
+	}
+}
+
+// Timeout bounds the context passed to each handler by d.
+func Timeout(d time.Duration) Middleware {
+	return func(next EventHandler) EventHandler {
+		return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next.HandleEvent(ctx, ev)
+		})
+	}
+}
+
+// Logging logs every handler call at debug level, along with its duration
+// and any error.
+func Logging(logger *zap.Logger) Middleware {
+	return func(next EventHandler) EventHandler {
+		name := handlerName(next)
+		return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+			start := time.Now()
+			err := next.HandleEvent(ctx, ev)
+
+			zfs := []zap.Field{
+				zap.String("handler", name),
+				zap.String("type", string(ev.Type)),
+				zap.String("event_id", ev.ID),
+				zap.Duration("duration", time.Since(start)),
+			}
+			if err != nil {
+				zfs = append(zfs, zap.Error(err))
+			}
+			logger.Debug("event handler called", zfs...)
+			return err
+		})
+	}
+}
+
+// Metrics records per-handler call counts and durations in m, keyed by
+// handler name, suffixed with "#" and the route ID when dispatched by an
+// EventRouter so that handlers of the same type are counted separately,
+// e.g. "*main.handler#3". Each handler's entry is a map of "calls",
+// "errors" and "duration_ns", the total time spent in the handler. A
+// handler stopping propagation is not counted as an error.
+func Metrics(m *expvar.Map) Middleware {
+	var mu sync.Mutex
+	handlerMetrics := func(name string) *expvar.Map {
+		mu.Lock()
+		defer mu.Unlock()
+		if hm, ok := m.Get(name).(*expvar.Map); ok {
+			return hm
+		}
+		hm := new(expvar.Map).Init()
+		m.Set(name, hm)
+		return hm
+	}
+
+	return func(next EventHandler) EventHandler {
+		name := handlerName(next)
+		return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+			hm := handlerMetrics(routeScope(ctx, name))
+
+			start := time.Now()
+			err := next.HandleEvent(ctx, ev)
+
+			hm.Add("calls", 1)
+			hm.Add("duration_ns", int64(time.Since(start)))
+			if isHandlerFailure(err) {
+				hm.Add("errors", 1)
+			}
+			return err
+		})
+	}
+}
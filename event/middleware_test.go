@@ -0,0 +1,177 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type panicHandler struct{}
+
+func (h *panicHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	panic("boom")
+}
+
+func (h *panicHandler) Handles() []EventType {
+	return []EventType{"test"}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next EventHandler) EventHandler {
+			return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+				order = append(order, name)
+				return next.HandleEvent(ctx, ev)
+			})
+		}
+	}
+
+	eh := Chain(&orderHandler{name: "handler", order: &order, types: []EventType{"test"}}, mw("outer"), mw("inner"))
+	eh.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+	if want := []string{"outer", "inner", "handler"}; !reflect.DeepEqual(want, order) {
+		t.Errorf("unexpected order: want=%v got=%v", want, order)
+	}
+	if want := []EventType{"test"}; !reflect.DeepEqual(want, eh.Handles()) {
+		t.Errorf("unexpected Handles: want=%v got=%v", want, eh.Handles())
+	}
+	if got := handlerName(eh); got != "*event.orderHandler" {
+		t.Errorf("unexpected name: %v", got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	eh := Recover(zap.New(core))(&panicHandler{})
+
+	err := eh.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+	var perr *PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+	if perr.Value != "boom" || perr.Handler != "*event.panicHandler" || len(perr.Stack) == 0 {
+		t.Errorf("unexpected panic error: %+v", perr)
+	}
+	if logs.FilterMessage("event handler panicked").Len() != 1 {
+		t.Errorf("expected panic to be logged")
+	}
+}
+
+func TestEventRouterUse(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+	er.ErrorPolicy = ErrorPolicyFailIfAny
+
+	eh := &fixtureHandler{}
+	er.Mount(&panicHandler{})
+	er.Mount(eh)
+
+	// applies to handlers mounted before Use
+	er.Use(Recover(zap.NewNop()))
+
+	err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+	var herr *HandlerErrors
+	if !errors.As(err, &herr) || len(herr.Errors) != 1 || herr.Errors[0].Handler != "*event.panicHandler" {
+		t.Fatalf("expected recovered panic to be reported, got %v", err)
+	}
+	if len(eh.events) != 1 {
+		t.Errorf("expected other handlers to still be called")
+	}
+
+	routes := er.Routes()
+	if routes[AnyEventType][0].Handler() != eh {
+		t.Errorf("expected Routes to report unwrapped handlers")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var active, maxSeen int32
+	eh := Timeout(10 * time.Millisecond)(&slowHandler{delay: time.Second, active: &active, maxSeen: &maxSeen})
+
+	if err := eh.HandleEvent(context.Background(), &Event{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	eh := Logging(zap.New(core))(&fixtureHandler{err: errors.New("failed")})
+
+	eh.HandleEvent(context.Background(), &Event{ID: "abc-123", Type: EventType("test")})
+
+	entries := logs.FilterMessage("event handler called").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["handler"] != "*event.fixtureHandler" || fields["event_id"] != "abc-123" || fields["error"] != "failed" {
+		t.Errorf("unexpected log fields: %v", fields)
+	}
+	if _, ok := fields["duration"]; !ok {
+		t.Errorf("expected duration to be logged")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := new(expvar.Map).Init()
+	ok := Metrics(m)(&fixtureHandler{})
+	failing := Metrics(m)(&slowHandler{name: "failing", err: errors.New("failed"), active: new(int32), maxSeen: new(int32)})
+
+	ok.HandleEvent(context.Background(), &Event{})
+	ok.HandleEvent(context.Background(), &Event{})
+	failing.HandleEvent(context.Background(), &Event{})
+
+	okm := m.Get("*event.fixtureHandler").(*expvar.Map)
+	if got := okm.Get("calls").String(); got != "2" {
+		t.Errorf("unexpected calls: want=2 got=%s", got)
+	}
+	if okm.Get("errors") != nil {
+		t.Errorf("unexpected errors: %v", okm.Get("errors"))
+	}
+	if okm.Get("duration_ns") == nil {
+		t.Errorf("expected duration to be recorded")
+	}
+
+	fm := m.Get("failing").(*expvar.Map)
+	if got := fm.Get("errors").String(); got != "1" {
+		t.Errorf("unexpected errors: want=1 got=%s", got)
+	}
+
+	stopping := Metrics(m)(&slowHandler{name: "stopping", err: ErrStopPropagation, active: new(int32), maxSeen: new(int32)})
+	stopping.HandleEvent(context.Background(), &Event{})
+	if sm := m.Get("stopping").(*expvar.Map); sm.Get("errors") != nil {
+		t.Errorf("unexpected errors: %v", sm.Get("errors"))
+	}
+}
+
+func TestMetricsScopedByRoute(t *testing.T) {
+	m := new(expvar.Map).Init()
+
+	er := NewEventRouter(zap.NewNop())
+	er.Use(Metrics(m))
+	rt1 := er.Mount(&fixtureHandler{})
+	rt2 := er.Mount(&fixtureHandler{types: []EventType{"test"}})
+
+	er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+	er.HandleEvent(context.Background(), &Event{Type: EventType("other")})
+
+	for rt, want := range map[*Route]string{rt1: "2", rt2: "1"} {
+		key := fmt.Sprintf("*event.fixtureHandler#%d", rt.ID())
+		hm, ok := m.Get(key).(*expvar.Map)
+		if !ok {
+			t.Fatalf("missing metrics for %s", key)
+		}
+		if got := hm.Get("calls").String(); got != want {
+			t.Errorf("%s: unexpected calls: want=%s got=%s", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// SetField replaces the value of the field with the given key, or appends
+// a new field if none exists. Any duplicates of the key are removed. Like
+// the other mutators, SetField acts on top-level keys only: a key such as
+// "a.b" names a field literally, not a path.
+func (ev *Event) SetField(key EventFieldKey, value interface{}) {
+	for i := range ev.Fields {
+		if ev.Fields[i].Key == key {
+			ev.Fields[i].Value = value
+			ev.removeFields(key, i+1)
+			return
+		}
+	}
+	ev.Fields = append(ev.Fields, Field(key, value))
+	ev.resetIndex()
+}
+
+// DeleteField removes every field with the given key, reporting whether
+// any were found.
+func (ev *Event) DeleteField(key EventFieldKey) bool {
+	n := len(ev.Fields)
+	ev.removeFields(key, 0)
+	return len(ev.Fields) != n
+}
+
+// removeFields drops fields with the given key at or after index from.
+func (ev *Event) removeFields(key EventFieldKey, from int) {
+	kept := ev.Fields[:from]
+	for _, ef := range ev.Fields[from:] {
+		if ef.Key != key {
+			kept = append(kept, ef)
+		}
+	}
+	// clear the tail so removed values may be garbage collected
+	for i := len(kept); i < len(ev.Fields); i++ {
+		ev.Fields[i] = EventField{}
+	}
+	if len(kept) != len(ev.Fields) {
+		ev.Fields = kept
+		ev.resetIndex()
+	}
+}
+
+// HasField reports whether a top-level field with the key exists. Paths
+// are not resolved, matching SetField and DeleteField; use Field to test
+// for a nested value.
+func (ev *Event) HasField(key EventFieldKey) bool {
+	_, ok := ev.lookup(key)
+	return ok
+}
+
+// Clone returns a deep copy of the event. Maps and slices within field
+// values are copied so that the clone may be mutated without affecting
+// the original, e.g. by handlers sharing an event dispatched by an
+// EventRouter. Pointers within field values are not followed.
+func (ev *Event) Clone() *Event {
+	out := Event{
+		ID:            ev.ID,
+		Type:          ev.Type,
+		Source:        ev.Source,
+		Time:          ev.Time,
+		CorrelationID: ev.CorrelationID,
+		CausationID:   ev.CausationID,
+		SchemaVersion: ev.SchemaVersion,
+	}
+	if ev.Fields != nil {
+		out.Fields = make([]EventField, len(ev.Fields))
+		for i, ef := range ev.Fields {
+			out.Fields[i] = EventField{Key: ef.Key, Value: deepCopyValue(ef.Value)}
+		}
+	}
+	out.resetIndex()
+	return &out
+}
+
+func deepCopyValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil, string, bool, float64, int, int64:
+		return v
+	case json.RawMessage:
+		return append(json.RawMessage(nil), v...)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = deepCopyValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = deepCopyValue(item)
+		}
+		return s
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return val
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), deepCopyReflect(iter.Value()))
+		}
+		return m.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return val
+		}
+		s := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			s.Index(i).Set(deepCopyReflect(rv.Index(i)))
+		}
+		return s.Interface()
+	}
+
+	return val
+}
+
+func deepCopyReflect(v reflect.Value) reflect.Value {
+	if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+		return v
+	}
+	cp := deepCopyValue(v.Interface())
+	if cp == nil {
+		return reflect.Zero(v.Type())
+	}
+	return reflect.ValueOf(cp).Convert(v.Type())
+}
+
+// Merge sets every field of other onto ev (see SetField), so that values
+// from other take precedence. Envelope metadata is left untouched.
+func (ev *Event) Merge(other *Event) {
+	for _, ef := range other.Fields {
+		ev.SetField(ef.Key, deepCopyValue(ef.Value))
+	}
+}
+
+// DuplicateKeyPolicy determines how events containing more than one field
+// with the same key are treated, see Event.Dedupe.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysAllow keeps duplicate fields as-is; accessors such as
+	// Field only ever see the first occurrence.
+	DuplicateKeysAllow DuplicateKeyPolicy = iota
+	// DuplicateKeysReject fails with ErrFieldDuplicate
+	DuplicateKeysReject
+	// DuplicateKeysFirstWins keeps only the first occurrence of each key
+	DuplicateKeysFirstWins
+	// DuplicateKeysLastWins keeps only the last occurrence of each key, in
+	// the position of the first occurrence
+	DuplicateKeysLastWins
+)
+
+// Dedupe applies the given policy to duplicated keys in ev.
+func (ev *Event) Dedupe(policy DuplicateKeyPolicy) error {
+	if policy == DuplicateKeysAllow {
+		return nil
+	}
+
+	pos := make(map[EventFieldKey]int, len(ev.Fields))
+	kept := make([]EventField, 0, len(ev.Fields))
+	for _, ef := range ev.Fields {
+		i, seen := pos[ef.Key]
+		if !seen {
+			pos[ef.Key] = len(kept)
+			kept = append(kept, ef)
+			continue
+		}
+
+		switch policy {
+		case DuplicateKeysReject:
+			return &FieldError{Key: ef.Key, Err: ErrFieldDuplicate}
+		case DuplicateKeysLastWins:
+			kept[i].Value = ef.Value
+		}
+	}
+
+	if len(kept) != len(ev.Fields) {
+		ev.Fields = kept
+		ev.resetIndex()
+	}
+	return nil
+}
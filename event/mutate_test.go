@@ -0,0 +1,126 @@
+package event
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEventSetDeleteHasField(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("a", 1),
+		Field("b", 2),
+		Field("a", 3),
+	)
+
+	ev.SetField("a", 4)
+	ev.SetField("c", 5)
+
+	want := []EventField{Field("a", 4), Field("b", 2), Field("c", 5)}
+	if !reflect.DeepEqual(want, ev.Fields) {
+		t.Errorf("unexpected fields after set: want=%+v got=%+v", want, ev.Fields)
+	}
+
+	if !ev.DeleteField("b") {
+		t.Errorf("expected DeleteField to report removal")
+	}
+	if ev.DeleteField("b") {
+		t.Errorf("expected DeleteField to report nothing removed")
+	}
+
+	if ev.HasField("b") || !ev.HasField("a") {
+		t.Errorf("unexpected HasField results")
+	}
+
+	want = []EventField{Field("a", 4), Field("c", 5)}
+	if !reflect.DeepEqual(want, ev.Fields) {
+		t.Errorf("unexpected fields after delete: want=%+v got=%+v", want, ev.Fields)
+	}
+	// keys are literal for every mutator and HasField alike
+	ev.SetField("d", map[string]interface{}{"e": 6})
+	if ev.HasField("d.e") {
+		t.Errorf("expected HasField not to resolve paths")
+	}
+	if ev.DeleteField("d.e") || !ev.HasField("d") {
+		t.Errorf("expected DeleteField not to resolve paths")
+	}
+	ev.SetField("d.e", 7)
+	if !ev.HasField("d.e") {
+		t.Errorf("expected HasField to find a literal dotted key")
+	}
+}
+
+func TestEventClone(t *testing.T) {
+	orig := NewEvent(EventType("example_type"),
+		Field("obj", map[string]interface{}{"list": []interface{}{"x"}}),
+		Field("tags", []string{"a"}),
+	)
+
+	clone := orig.Clone()
+	if !reflect.DeepEqual(orig, clone) {
+		t.Fatalf("clone differs from original: want=%+v got=%+v", orig, clone)
+	}
+
+	clone.Fields[0].Value.(map[string]interface{})["list"].([]interface{})[0] = "y"
+	clone.Fields[1].Value.([]string)[0] = "b"
+	clone.SetField("new", true)
+
+	if got, _ := orig.StringField("obj.list[0]"); got != "x" {
+		t.Errorf("mutating clone changed nested original value: %v", got)
+	}
+	if got, _ := orig.StringSliceField("tags"); got[0] != "a" {
+		t.Errorf("mutating clone changed original slice: %v", got)
+	}
+	if orig.HasField("new") {
+		t.Errorf("mutating clone changed original fields")
+	}
+}
+
+func TestEventMerge(t *testing.T) {
+	ev := NewEvent(EventType("example_type"), Field("a", 1), Field("b", 2))
+	other := NewEvent(EventType("other_type"), Field("b", 3), Field("c", 4))
+
+	ev.Merge(other)
+
+	want := []EventField{Field("a", 1), Field("b", 3), Field("c", 4)}
+	if !reflect.DeepEqual(want, ev.Fields) {
+		t.Errorf("unexpected fields after merge: want=%+v got=%+v", want, ev.Fields)
+	}
+	if ev.Type != EventType("example_type") {
+		t.Errorf("merge changed event type: %v", ev.Type)
+	}
+}
+
+func TestEventDedupe(t *testing.T) {
+	raw := []byte(`{"type": "example_type", "fields": [
+		{"key": "a", "value": 1}, {"key": "b", "value": 2}, {"key": "a", "value": 3}
+	]}`)
+
+	tests := []struct {
+		policy DuplicateKeyPolicy
+		want   []EventField
+	}{
+		{DuplicateKeysAllow, []EventField{Field("a", 1.0), Field("b", 2.0), Field("a", 3.0)}},
+		{DuplicateKeysFirstWins, []EventField{Field("a", 1.0), Field("b", 2.0)}},
+		{DuplicateKeysLastWins, []EventField{Field("a", 3.0), Field("b", 2.0)}},
+	}
+
+	for _, tt := range tests {
+		var ev Event
+		if err := (&JSONCodec{DuplicateKeys: tt.policy}).Unmarshal(raw, &ev); err != nil {
+			t.Errorf("policy %d: unexpected error: %v", tt.policy, err)
+			continue
+		}
+		if !reflect.DeepEqual(tt.want, ev.Fields) {
+			t.Errorf("policy %d: unexpected fields: want=%+v got=%+v", tt.policy, tt.want, ev.Fields)
+		}
+	}
+
+	var ev Event
+	err := (&JSONCodec{DuplicateKeys: DuplicateKeysReject}).Unmarshal(raw, &ev)
+
+	var ferr *FieldError
+	if !errors.Is(err, ErrFieldDuplicate) || !errors.As(err, &ferr) || ferr.Key != "a" {
+		t.Errorf("expected ErrFieldDuplicate naming key a, got %v", err)
+	}
+}
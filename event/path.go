@@ -0,0 +1,160 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Field keys may address values nested within a field using a path such as
+// "location.country" or "readings[2].value" (equivalently "readings.2.value").
+// The first path segment(s) name a top-level field, and the remainder walks
+// into the map[string]interface{} and []interface{} values produced by JSON
+// decoding. A top-level key containing a literal "." always takes
+// precedence over a nested path.
+
+type pathSegment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func isPath(key EventFieldKey) bool {
+	return strings.ContainsAny(string(key), ".[")
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		var idxs []string
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.IndexByte(rest, ']')
+				if rest[0] != '[' || end < 0 {
+					return nil, fmt.Errorf("malformed path segment %q", part)
+				}
+				idxs = append(idxs, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		if name != "" {
+			if n, err := strconv.Atoi(name); err == nil && n >= 0 {
+				segs = append(segs, pathSegment{key: name, index: n, isIdx: true})
+			} else {
+				segs = append(segs, pathSegment{key: name})
+			}
+		} else if len(idxs) == 0 {
+			return nil, errors.New("empty path segment")
+		}
+
+		for _, idx := range idxs {
+			n, err := strconv.Atoi(idx)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("malformed path index %q", idx)
+			}
+			segs = append(segs, pathSegment{key: idx, index: n, isIdx: true})
+		}
+	}
+	return segs, nil
+}
+
+// lookupPath resolves a nested path, trying the longest dotted prefix that
+// names a top-level field first.
+func (ev *Event) lookupPath(key EventFieldKey) (interface{}, error) {
+	path := string(key)
+	for end := len(path); end > 0; end = strings.LastIndexAny(path[:end], ".[") {
+		root, ok := ev.lookup(EventFieldKey(path[:end]))
+		if !ok {
+			continue
+		}
+
+		rest := strings.TrimPrefix(path[end:], ".")
+		if rest == "" {
+			return root, nil
+		}
+
+		root, err := resolveValue(EventFieldKey(path[:end]), root)
+		if err != nil {
+			return nil, err
+		}
+
+		segs, err := parsePath(rest)
+		if err != nil {
+			return nil, newFieldError(key, "path", nil, ErrFieldUnexpectedValue, err)
+		}
+		return walkPath(key, root, segs)
+	}
+
+	return nil, &FieldError{Key: key, Err: ErrFieldMissing}
+}
+
+func walkPath(key EventFieldKey, val interface{}, segs []pathSegment) (interface{}, error) {
+	for _, seg := range segs {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			next, ok := v[seg.key]
+			if !ok {
+				return nil, &FieldError{Key: key, Err: ErrFieldMissing}
+			}
+			val = next
+			continue
+		case []interface{}:
+			if !seg.isIdx {
+				return nil, newFieldError(key, "object", val, ErrFieldIncorrectType, nil)
+			}
+			if seg.index >= len(v) {
+				return nil, &FieldError{Key: key, Err: ErrFieldMissing}
+			}
+			val = v[seg.index]
+			continue
+		}
+
+		// fall back to reflection for values built in-process, e.g.
+		// map[string]string or []int
+		rv := reflect.ValueOf(val)
+		switch {
+		case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+			next := rv.MapIndex(reflect.ValueOf(seg.key).Convert(rv.Type().Key()))
+			if !next.IsValid() {
+				return nil, &FieldError{Key: key, Err: ErrFieldMissing}
+			}
+			val = next.Interface()
+		case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && seg.isIdx:
+			if seg.index >= rv.Len() {
+				return nil, &FieldError{Key: key, Err: ErrFieldMissing}
+			}
+			val = rv.Index(seg.index).Interface()
+		case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+			return nil, newFieldError(key, "object", val, ErrFieldIncorrectType, nil)
+		default:
+			want := "object"
+			if seg.isIdx {
+				want = "array"
+			}
+			return nil, newFieldError(key, want, val, ErrFieldIncorrectType, nil)
+		}
+	}
+	return val, nil
+}
+
+// Map returns the event fields keyed by field key. As with Field, the first
+// occurrence of a duplicated key wins and lazily-decoded values are
+// decoded.
+func (ev *Event) Map() map[EventFieldKey]interface{} {
+	m := make(map[EventFieldKey]interface{}, len(ev.Fields))
+	for i := len(ev.Fields) - 1; i >= 0; i-- {
+		ef := ev.Fields[i]
+		if val, err := resolveValue(ef.Key, ef.Value); err == nil {
+			m[ef.Key] = val
+		} else {
+			m[ef.Key] = ef.Value
+		}
+	}
+	return m
+}
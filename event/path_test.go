@@ -0,0 +1,88 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFieldPath(t *testing.T) {
+	raw := `{
+  "type": "example_type",
+  "fields": [
+    {"key": "location", "value": {"country": "FR", "coords": {"lat": 48.85, "lng": 2.35}}},
+    {"key": "readings", "value": [{"value": 1}, {"value": 2.5}]},
+    {"key": "dotted.key", "value": {"inner": "XYZ"}},
+    {"key": "scalar", "value": "XYZ"}
+  ]
+}`
+	var ev Event
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatalf("failed unmarshaling JSON: %v", err)
+	}
+
+	if got, err := ev.StringField(EventFieldKey("location.country")); err != nil || got != "FR" {
+		t.Errorf("unexpected nested string: got=%v err=%v", got, err)
+	}
+	if got, err := ev.FloatField(EventFieldKey("location.coords.lat")); err != nil || got != 48.85 {
+		t.Errorf("unexpected nested float: got=%v err=%v", got, err)
+	}
+	if got, err := ev.FloatField(EventFieldKey("readings[1].value")); err != nil || got != 2.5 {
+		t.Errorf("unexpected indexed float: got=%v err=%v", got, err)
+	}
+	if got, err := ev.IntField(EventFieldKey("readings.0.value")); err != nil || got != 1 {
+		t.Errorf("unexpected indexed int: got=%v err=%v", got, err)
+	}
+	if got, err := ev.StringField(EventFieldKey("dotted.key.inner")); err != nil || got != "XYZ" {
+		t.Errorf("unexpected dotted key lookup: got=%v err=%v", got, err)
+	}
+
+	var coords struct{ Lat, Lng float64 }
+	if err := ev.JSONField(EventFieldKey("location.coords"), &coords); err != nil || coords.Lng != 2.35 {
+		t.Errorf("unexpected nested JSON: got=%+v err=%v", coords, err)
+	}
+
+	missing := []EventFieldKey{"location.city", "readings[5].value", "nope.country"}
+	for _, key := range missing {
+		if _, err := ev.Field(key); !errors.Is(err, ErrFieldMissing) {
+			t.Errorf("%s: expected ErrFieldMissing, got %v", key, err)
+		}
+	}
+
+	incorrect := []EventFieldKey{"scalar.inner", "readings.value", "location.country[0]"}
+	for _, key := range incorrect {
+		var ferr *FieldError
+		_, err := ev.Detailed().Field(key)
+		if !errors.Is(err, ErrFieldIncorrectType) || !errors.As(err, &ferr) || ferr.Key != key {
+			t.Errorf("%s: expected ErrFieldIncorrectType naming the full path, got %v", key, err)
+		}
+	}
+}
+
+func TestFieldPathNative(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("labels", map[string]string{"env": "prod"}),
+		Field("sizes", []int{1, 2, 3}),
+	)
+
+	if got, err := ev.StringField(EventFieldKey("labels.env")); err != nil || got != "prod" {
+		t.Errorf("unexpected nested string: got=%v err=%v", got, err)
+	}
+	if got, err := ev.IntField(EventFieldKey("sizes[2]")); err != nil || got != 3 {
+		t.Errorf("unexpected indexed int: got=%v err=%v", got, err)
+	}
+}
+
+func TestEventMap(t *testing.T) {
+	ev := NewEvent(EventType("example_type"),
+		Field("a", 1),
+		Field("b", 2),
+		Field("a", 3),
+	)
+
+	want := map[EventFieldKey]interface{}{"a": 1, "b": 2}
+	if got := ev.Map(); !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected map: want=%v got=%v", want, got)
+	}
+}
@@ -0,0 +1,85 @@
+package event
+
+import (
+	"strings"
+)
+
+// Event types are dot-separated hierarchies such as
+// "dataset.ingest.completed". Handlers may subscribe to patterns of types
+// by returning them from Handles, where a "*" segment matches exactly one
+// segment and a "#" segment matches zero or more segments, e.g.
+// "dataset.ingest.*" or "dataset.#". Note that "dataset.#" matches
+// "dataset" itself.
+
+const (
+	patternSegmentOne  = "*"
+	patternSegmentMany = "#"
+)
+
+// IsEventTypePattern reports whether typ contains wildcard segments.
+func IsEventTypePattern(typ EventType) bool {
+	for _, seg := range strings.Split(string(typ), ".") {
+		if seg == patternSegmentOne || seg == patternSegmentMany {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchEventType reports whether typ matches pattern. A pattern without
+// wildcards matches only an identical type.
+func MatchEventType(pattern, typ EventType) bool {
+	return matchSegments(strings.Split(string(pattern), "."), strings.Split(string(typ), "."))
+}
+
+func matchSegments(pattern, typ []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case patternSegmentMany:
+			for i := 0; i <= len(typ); i++ {
+				if matchSegments(pattern[1:], typ[i:]) {
+					return true
+				}
+			}
+			return false
+		case patternSegmentOne:
+			if len(typ) == 0 {
+				return false
+			}
+		default:
+			if len(typ) == 0 || pattern[0] != typ[0] {
+				return false
+			}
+		}
+		pattern, typ = pattern[1:], typ[1:]
+	}
+	return len(typ) == 0
+}
+
+// patternSpecificity orders patterns from most to least specific: more
+// literal segments first, then fewer "#" segments.
+type patternSpecificity struct {
+	literals int
+	many     int
+}
+
+func specificityOf(pattern EventType) patternSpecificity {
+	var s patternSpecificity
+	for _, seg := range strings.Split(string(pattern), ".") {
+		switch seg {
+		case patternSegmentOne:
+		case patternSegmentMany:
+			s.many++
+		default:
+			s.literals++
+		}
+	}
+	return s
+}
+
+func (s patternSpecificity) moreSpecificThan(o patternSpecificity) bool {
+	if s.literals != o.literals {
+		return s.literals > o.literals
+	}
+	return s.many < o.many
+}
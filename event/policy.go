@@ -0,0 +1,119 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorPolicy determines whether EventRouter.HandleEvent reports handler
+// failures to its caller. Failures are logged under every policy.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyIgnore never returns an error
+	ErrorPolicyIgnore ErrorPolicy = iota
+	// ErrorPolicyFailFast stops dispatch at the first failure and returns
+	// it. Handlers already running in parallel have their context
+	// canceled.
+	ErrorPolicyFailFast
+	// ErrorPolicyFailIfAny runs every handler, then returns all failures
+	ErrorPolicyFailIfAny
+	// ErrorPolicyFailIfAll runs every handler, returning their failures
+	// only if none succeeded
+	ErrorPolicyFailIfAll
+)
+
+var errorPolicyNames = map[ErrorPolicy]string{
+	ErrorPolicyIgnore:    "ignore",
+	ErrorPolicyFailFast:  "fail-fast",
+	ErrorPolicyFailIfAny: "fail-if-any",
+	ErrorPolicyFailIfAll: "fail-if-all",
+}
+
+func (p ErrorPolicy) String() string {
+	if name, ok := errorPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorPolicy(%d)", int(p))
+}
+
+// ParseErrorPolicy parses the name of an ErrorPolicy, e.g. "fail-if-any".
+// An empty string selects ErrorPolicyIgnore.
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	if s == "" {
+		return ErrorPolicyIgnore, nil
+	}
+	for p, name := range errorPolicyNames {
+		if strings.EqualFold(s, name) {
+			return p, nil
+		}
+	}
+	return ErrorPolicyIgnore, fmt.Errorf("unknown error policy %q", s)
+}
+
+// ErrStopPropagation may be returned by a handler to prevent the
+// EventRouter calling any handler after it. Returned as-is it is not
+// treated as a failure; wrap an error with StopPropagation to both stop
+// propagation and report the failure.
+var ErrStopPropagation = errors.New("event propagation stopped")
+
+// StopPropagation wraps err so that it stops propagation (see
+// ErrStopPropagation) and is still treated as a handler failure.
+func StopPropagation(err error) error {
+	return &stopPropagationError{err: err}
+}
+
+type stopPropagationError struct {
+	err error
+}
+
+func (e *stopPropagationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stopPropagationError) Is(target error) bool {
+	return target == ErrStopPropagation
+}
+
+func (e *stopPropagationError) Unwrap() error {
+	return e.err
+}
+
+// HandlerError is the failure of a single handler.
+type HandlerError struct {
+	Handler string
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("handler %s: %v", e.Handler, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerErrors aggregates handler failures returned by an EventRouter, in
+// handler order.
+type HandlerErrors struct {
+	Errors []*HandlerError
+}
+
+func (e *HandlerErrors) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("event handlers failed: %s", strings.Join(msgs, "; "))
+}
+
+// Is reports whether any of the aggregated errors matches target.
+func (e *HandlerErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
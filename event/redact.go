@@ -0,0 +1,267 @@
+package event
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"strings"
+)
+
+// RedactedValue replaces sensitive field values in RedactMask mode.
+const RedactedValue = "[REDACTED]"
+
+type RedactMode int
+
+const (
+	// RedactMask replaces sensitive values with RedactedValue
+	RedactMask RedactMode = iota
+	// RedactStrip removes sensitive fields entirely
+	RedactStrip
+	// RedactHash replaces sensitive values with a "sha256:"-prefixed hex
+	// digest of their JSON encoding, so that equal values may still be
+	// correlated without being revealed
+	RedactHash
+)
+
+// Redactor identifies sensitive event fields and removes their values
+// before events are logged or leave the process.
+type Redactor struct {
+	// Keys are sensitive in events of any type. A key may be a path as
+	// accepted by Field, e.g. "customer.address", to redact a value nested
+	// within a JSON object or array field while leaving the rest of the
+	// field intact. Values built in-process, such as structs or
+	// map[string]string, are walked as their JSON encoding; a value that
+	// cannot be encoded is masked whole.
+	Keys []EventFieldKey
+	// Types have every field treated as sensitive
+	Types []EventType
+
+	Mode RedactMode
+
+	// HashKey, if set, makes RedactHash an HMAC-SHA256 keyed digest,
+	// preventing low-entropy values from being recovered by brute force
+	HashKey []byte
+}
+
+// IsSensitive reports whether the field with the given key should be
+// redacted from an event of the given type. Paths within a sensitive key,
+// such as "customer.address.city" for "customer.address", are sensitive
+// too. A nil Redactor treats nothing as sensitive.
+func (r *Redactor) IsSensitive(typ EventType, key EventFieldKey) bool {
+	if r == nil {
+		return false
+	}
+	for _, t := range r.Types {
+		if t == typ {
+			return true
+		}
+	}
+	for _, k := range r.Keys {
+		if k == key || isPathPrefix(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNested reports whether any sensitive path lies within the field with
+// the given key.
+func (r *Redactor) hasNested(key EventFieldKey) bool {
+	if r == nil {
+		return false
+	}
+	for _, k := range r.Keys {
+		if isPathPrefix(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPathPrefix reports whether key is a path nested within prefix.
+func isPathPrefix(prefix, key EventFieldKey) bool {
+	p, k := string(prefix), string(key)
+	return len(k) > len(p) && strings.HasPrefix(k, p) && (k[len(p)] == '.' || k[len(p)] == '[')
+}
+
+// Redact returns a copy of ev with sensitive fields redacted according to
+// Mode. ev itself is never modified, and is returned as-is if it contains
+// no sensitive fields.
+func (r *Redactor) Redact(ev *Event) *Event {
+	sensitive := false
+	for _, ef := range ev.Fields {
+		if r.IsSensitive(ev.Type, ef.Key) || r.hasNested(ef.Key) {
+			sensitive = true
+			break
+		}
+	}
+	if !sensitive {
+		return ev
+	}
+
+	out := ev.Clone()
+	fields := out.Fields[:0]
+	for _, ef := range out.Fields {
+		val, ok := r.redactField(ev.Type, ef, r.Mode)
+		if !ok {
+			continue
+		}
+		ef.Value = val
+		fields = append(fields, ef)
+	}
+	out.Fields = fields
+	return out
+}
+
+// redactField returns the redacted value of ef, or false if the field is
+// to be stripped entirely. Nested values are redacted in place, so ef must
+// not share them with the caller's event.
+func (r *Redactor) redactField(typ EventType, ef EventField, mode RedactMode) (interface{}, bool) {
+	if r.IsSensitive(typ, ef.Key) {
+		switch mode {
+		case RedactStrip:
+			return nil, false
+		case RedactHash:
+			return r.hash(ef.Key, ef.Value), true
+		}
+		return RedactedValue, true
+	}
+
+	val := ef.Value
+	for _, k := range r.Keys {
+		if !isPathPrefix(ef.Key, k) {
+			continue
+		}
+		segs, err := parsePath(strings.TrimPrefix(string(k[len(ef.Key):]), "."))
+		if err != nil {
+			continue
+		}
+		var ok bool
+		if val, err = resolveValue(ef.Key, val); err == nil {
+			val, ok = jsonTree(val)
+		}
+		if !ok {
+			// a malformed value cannot be inspected, so hide all of it
+			return RedactedValue, true
+		}
+		r.redactPath(ef.Key, val, segs, mode)
+	}
+	return val, true
+}
+
+// redactPath walks segs within val, redacting the value found at the end.
+func (r *Redactor) redactPath(key EventFieldKey, val interface{}, segs []pathSegment, mode RedactMode) {
+	seg, last := segs[0], len(segs) == 1
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		item, ok := v[seg.key]
+		if !ok {
+			return
+		}
+		if !last {
+			if item, ok = jsonTree(item); !ok {
+				v[seg.key] = RedactedValue
+				return
+			}
+			v[seg.key] = item
+			r.redactPath(key, item, segs[1:], mode)
+			return
+		}
+		switch mode {
+		case RedactStrip:
+			delete(v, seg.key)
+		case RedactHash:
+			v[seg.key] = r.hash(key, item)
+		default:
+			v[seg.key] = RedactedValue
+		}
+	case []interface{}:
+		if !seg.isIdx || seg.index >= len(v) {
+			return
+		}
+		if !last {
+			item, ok := jsonTree(v[seg.index])
+			if !ok {
+				v[seg.index] = RedactedValue
+				return
+			}
+			v[seg.index] = item
+			r.redactPath(key, item, segs[1:], mode)
+			return
+		}
+		switch mode {
+		case RedactStrip:
+			// removing an element would shift the others, so blank it
+			v[seg.index] = nil
+		case RedactHash:
+			v[seg.index] = r.hash(key, v[seg.index])
+		default:
+			v[seg.index] = RedactedValue
+		}
+	}
+}
+
+// jsonTree returns val as decoded from its JSON encoding, so that paths
+// can be walked through structs, typed maps and slices alike. It returns
+// false if val cannot be encoded.
+func jsonTree(val interface{}) (interface{}, bool) {
+	switch val.(type) {
+	case nil, map[string]interface{}, []interface{}, string, float64, bool:
+		return val, true
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, false
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (r *Redactor) hash(key EventFieldKey, val interface{}) string {
+	// lazily-decoded values are normalized so that the digest does not
+	// depend on how the event arrived
+	if v, err := resolveValue(key, val); err == nil {
+		val = v
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return RedactedValue
+	}
+
+	var h hash.Hash
+	if len(r.HashKey) > 0 {
+		h = hmac.New(sha256.New, r.HashKey)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(data)
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// NewRedactingHandler passes events to next with sensitive fields
+// redacted, e.g. ahead of a publisher on an outbound EventRouter.
+func NewRedactingHandler(r *Redactor, next EventHandler) EventHandler {
+	return &redactingHandler{redactor: r, next: next}
+}
+
+type redactingHandler struct {
+	redactor *Redactor
+	next     EventHandler
+}
+
+func (h *redactingHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	return h.next.HandleEvent(ctx, h.redactor.Redact(ev))
+}
+
+func (h *redactingHandler) Handles() []EventType {
+	return h.next.Handles()
+}
@@ -0,0 +1,201 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func redactFixture() *Event {
+	return &Event{
+		Type: EventType("asset_created"),
+		Fields: []EventField{
+			Field("name", "XYZ"),
+			Field("address", "1 Main St"),
+			Field("location", map[string]interface{}{"lat": 1.5}),
+		},
+	}
+}
+
+func TestRedactorModes(t *testing.T) {
+	tests := []struct {
+		mode RedactMode
+		want []EventField
+	}{
+		{
+			mode: RedactMask,
+			want: []EventField{
+				Field("name", "XYZ"),
+				Field("address", RedactedValue),
+				Field("location", RedactedValue),
+			},
+		},
+		{
+			mode: RedactStrip,
+			want: []EventField{
+				Field("name", "XYZ"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		ev := redactFixture()
+		r := &Redactor{Keys: []EventFieldKey{"address", "location"}, Mode: tt.mode}
+
+		got := r.Redact(ev)
+		if !reflect.DeepEqual(tt.want, got.Fields) {
+			t.Errorf("mode=%d: unexpected fields: want=%v got=%v", tt.mode, tt.want, got.Fields)
+		}
+		if !reflect.DeepEqual(redactFixture(), ev) {
+			t.Errorf("mode=%d: original event modified", tt.mode)
+		}
+	}
+}
+
+func TestRedactorHash(t *testing.T) {
+	r := &Redactor{Keys: []EventFieldKey{"address"}, Mode: RedactHash}
+
+	got, err := r.Redact(redactFixture()).StringField("address")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "sha256:") || len(got) != len("sha256:")+64 {
+		t.Errorf("unexpected hash: %v", got)
+	}
+
+	again, _ := r.Redact(redactFixture()).StringField("address")
+	if got != again {
+		t.Errorf("hash not stable: %v != %v", got, again)
+	}
+
+	keyed := &Redactor{Keys: []EventFieldKey{"address"}, Mode: RedactHash, HashKey: []byte("secret")}
+	if other, _ := keyed.Redact(redactFixture()).StringField("address"); other == got {
+		t.Errorf("expected keyed hash to differ from unkeyed hash")
+	}
+}
+
+func TestRedactorTypes(t *testing.T) {
+	r := &Redactor{Types: []EventType{"asset_created"}}
+
+	got := r.Redact(redactFixture())
+	for _, ef := range got.Fields {
+		if ef.Value != RedactedValue {
+			t.Errorf("field %s not redacted: %v", ef.Key, ef.Value)
+		}
+	}
+
+	ev := &Event{Type: EventType("other"), Fields: []EventField{Field("name", "XYZ")}}
+	if r.Redact(ev) != ev {
+		t.Errorf("expected event without sensitive fields to be returned as-is")
+	}
+}
+
+func TestRedactingHandler(t *testing.T) {
+	next := &fixtureHandler{types: []EventType{"asset_created"}}
+	eh := NewRedactingHandler(&Redactor{Keys: []EventFieldKey{"address"}, Mode: RedactStrip}, next)
+
+	if !reflect.DeepEqual(next.types, eh.Handles()) {
+		t.Errorf("unexpected Handles: %v", eh.Handles())
+	}
+
+	if err := eh.HandleEvent(context.Background(), redactFixture()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(next.events) != 1 || next.events[0].HasField("address") {
+		t.Errorf("sensitive field reached next handler: %+v", next.events)
+	}
+}
+
+func TestRedactorNestedKeys(t *testing.T) {
+	fixture := func() *Event {
+		return &Event{
+			Type: EventType("order_created"),
+			Fields: []EventField{
+				Field("customer", map[string]interface{}{
+					"name":    "XYZ",
+					"address": map[string]interface{}{"city": "Paris"},
+				}),
+				Field("items", json.RawMessage(`[{"sku":"a","card":"4111"}]`)),
+			},
+		}
+	}
+
+	tests := []struct {
+		mode RedactMode
+		want []EventField
+	}{
+		{
+			mode: RedactMask,
+			want: []EventField{
+				Field("customer", map[string]interface{}{"name": "XYZ", "address": RedactedValue}),
+				Field("items", []interface{}{map[string]interface{}{"sku": "a", "card": RedactedValue}}),
+			},
+		},
+		{
+			mode: RedactStrip,
+			want: []EventField{
+				Field("customer", map[string]interface{}{"name": "XYZ"}),
+				Field("items", []interface{}{map[string]interface{}{"sku": "a"}}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		ev := fixture()
+		r := &Redactor{Keys: []EventFieldKey{"customer.address", "items[0].card"}, Mode: tt.mode}
+
+		got := r.Redact(ev)
+		if !reflect.DeepEqual(tt.want, got.Fields) {
+			t.Errorf("mode=%d: unexpected fields: want=%v got=%v", tt.mode, tt.want, got.Fields)
+		}
+		if !reflect.DeepEqual(fixture(), ev) {
+			t.Errorf("mode=%d: original event modified", tt.mode)
+		}
+	}
+
+	r := &Redactor{Keys: []EventFieldKey{"customer.address"}}
+	if !r.IsSensitive("order_created", "customer.address.city") {
+		t.Errorf("expected path within sensitive key to be sensitive")
+	}
+	if r.IsSensitive("order_created", "customer") || r.IsSensitive("order_created", "customer.addresses") {
+		t.Errorf("unexpected sensitive key")
+	}
+}
+
+func TestRedactorNestedTypedValues(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	}
+
+	ev := &Event{
+		Type: EventType("order_created"),
+		Fields: []EventField{
+			Field("billing", address{City: "Paris", Zip: "75001"}),
+			Field("tags", map[string]string{"card": "4111", "sku": "a"}),
+			Field("customer", map[string]interface{}{"address": address{City: "Paris", Zip: "75001"}}),
+			Field("stream", make(chan int)),
+		},
+	}
+
+	r := &Redactor{Keys: []EventFieldKey{"billing.zip", "tags.card", "customer.address.zip", "stream.value"}}
+	got := r.Redact(ev)
+
+	want := []EventField{
+		Field("billing", map[string]interface{}{"city": "Paris", "zip": RedactedValue}),
+		Field("tags", map[string]interface{}{"card": RedactedValue, "sku": "a"}),
+		Field("customer", map[string]interface{}{"address": map[string]interface{}{"city": "Paris", "zip": RedactedValue}}),
+		Field("stream", RedactedValue),
+	}
+	if !reflect.DeepEqual(want, got.Fields) {
+		t.Errorf("unexpected fields: want=%v got=%v", want, got.Fields)
+	}
+	if tags := ev.Fields[1].Value.(map[string]string); tags["card"] != "4111" {
+		t.Errorf("original event modified: %v", tags)
+	}
+	if _, ok := ev.Fields[2].Value.(map[string]interface{})["address"].(address); !ok {
+		t.Errorf("original event modified: %v", ev.Fields[2].Value)
+	}
+}
@@ -0,0 +1,177 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type RetryConfig struct {
+	// MaxAttempts bounds the number of calls to the handler, including
+	// the first; defaults to 3
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry, defaulting to
+	// 100ms. Each subsequent delay is multiplied by Multiplier (default 2)
+	// up to MaxBackoff (default 10s).
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter randomizes each delay by up to the given fraction in either
+	// direction, e.g. 0.2 for ±20%; zero disables jitter
+	Jitter float64
+
+	// Retryable classifies handler errors. By default every error is
+	// retried unless marked with Permanent or wrapping
+	// ErrStopPropagation.
+	Retryable func(error) bool
+
+	// Logger, if set, logs each retry
+	Logger *zap.Logger
+}
+
+func (cfg *RetryConfig) setDefaults() {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 10 * time.Second
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = 2
+	}
+	if cfg.Retryable == nil {
+		cfg.Retryable = isRetryable
+	}
+}
+
+// backoff returns the delay before the given retry, counting from one.
+func (cfg *RetryConfig) backoff(retry int) time.Duration {
+	d := float64(cfg.InitialBackoff) * math.Pow(cfg.Multiplier, float64(retry-1))
+	if d > float64(cfg.MaxBackoff) {
+		d = float64(cfg.MaxBackoff)
+	}
+	if cfg.Jitter > 0 {
+		d *= 1 + cfg.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+func isRetryable(err error) bool {
+	return !IsPermanent(err) && !errors.Is(err, ErrStopPropagation)
+}
+
+// Permanent marks err as not worth retrying, see Retry.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or any error it wraps, was marked with
+// Permanent.
+func IsPermanent(err error) bool {
+	var perr *permanentError
+	return errors.As(err, &perr)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// RetryError is returned once a handler has been retried without success.
+type RetryError struct {
+	Attempts int
+	// Err is the error returned by the final attempt
+	Err error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Retry calls the wrapped handler again when it fails with a retryable
+// error, waiting with exponential backoff between attempts. Retries stop
+// early if the context is done. Errors from handlers that were never
+// retried are returned as-is, and otherwise as a *RetryError.
+func Retry(cfg RetryConfig) Middleware {
+	cfg.setDefaults()
+
+	return func(next EventHandler) EventHandler {
+		name := handlerName(next)
+		return WrapHandler(next, func(ctx context.Context, ev *Event) error {
+			var err error
+			attempt := 0
+			for attempt < cfg.MaxAttempts {
+				attempt++
+
+				if err = next.HandleEvent(ctx, ev); err == nil {
+					return nil
+				}
+				if attempt == cfg.MaxAttempts || !cfg.Retryable(err) {
+					break
+				}
+
+				delay := cfg.backoff(attempt)
+				if cfg.Logger != nil {
+					cfg.Logger.Warn("retrying event handler",
+						zap.String("handler", name),
+						zap.String("type", string(ev.Type)),
+						zap.Int("attempt", attempt),
+						zap.Duration("backoff", delay),
+						zap.Error(err),
+					)
+				}
+
+				if !sleepContext(ctx, delay) {
+					break
+				}
+			}
+
+			if attempt == 1 {
+				return err
+			}
+			return &RetryError{Attempts: attempt, Err: err}
+		})
+	}
+}
+
+// NewRetryHandler wraps eh with Retry.
+func NewRetryHandler(eh EventHandler, cfg RetryConfig) EventHandler {
+	return Retry(cfg)(eh)
+}
+
+// sleepContext waits for d, reporting false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
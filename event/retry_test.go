@@ -0,0 +1,160 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type flakyHandler struct {
+	failures int
+	err      error
+
+	calls int
+}
+
+func (h *flakyHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	h.calls++
+	if h.calls <= h.failures {
+		return h.err
+	}
+	return nil
+}
+
+func (h *flakyHandler) Handles() []EventType {
+	return []EventType{"test"}
+}
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", failures: 0, err: errTransient, wantCalls: 1},
+		{name: "recovers", failures: 2, err: errTransient, wantCalls: 3},
+		{name: "exhausted", failures: 5, err: errTransient, wantCalls: 3, wantErr: true},
+		{name: "permanent", failures: 5, err: Permanent(errTransient), wantCalls: 1, wantErr: true},
+		{name: "stop propagation", failures: 5, err: ErrStopPropagation, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		eh := &flakyHandler{failures: tt.failures, err: tt.err}
+		rh := NewRetryHandler(eh, RetryConfig{InitialBackoff: time.Microsecond, Logger: zap.NewNop()})
+
+		err := rh.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error to wrap %v, got %v", tt.name, tt.err, err)
+		}
+		if eh.calls != tt.wantCalls {
+			t.Errorf("%s: unexpected calls: want=%d got=%d", tt.name, tt.wantCalls, eh.calls)
+		}
+	}
+}
+
+func TestRetryError(t *testing.T) {
+	errTransient := errors.New("transient")
+	rh := NewRetryHandler(&flakyHandler{failures: 5, err: errTransient}, RetryConfig{
+		MaxAttempts:    4,
+		InitialBackoff: time.Microsecond,
+	})
+
+	err := rh.HandleEvent(context.Background(), &Event{})
+
+	var rerr *RetryError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RetryError, got %v", err)
+	}
+	if rerr.Attempts != 4 || rerr.Err != errTransient {
+		t.Errorf("unexpected retry error: %+v", rerr)
+	}
+
+	// errors from handlers that were never retried are returned as-is
+	perr := Permanent(errTransient)
+	rh = NewRetryHandler(&flakyHandler{failures: 1, err: perr}, RetryConfig{})
+	if err := rh.HandleEvent(context.Background(), &Event{}); err != perr {
+		t.Errorf("expected unwrapped permanent error, got %v", err)
+	}
+}
+
+func TestRetryClassifier(t *testing.T) {
+	errFatal := errors.New("fatal")
+	eh := &flakyHandler{failures: 5, err: errFatal}
+	rh := NewRetryHandler(eh, RetryConfig{
+		InitialBackoff: time.Microsecond,
+		Retryable:      func(err error) bool { return err != errFatal },
+	})
+
+	rh.HandleEvent(context.Background(), &Event{})
+	if eh.calls != 1 {
+		t.Errorf("expected classifier to prevent retries, got %d calls", eh.calls)
+	}
+}
+
+func TestRetryContextCanceled(t *testing.T) {
+	eh := &flakyHandler{failures: 5, err: errors.New("transient")}
+	rh := NewRetryHandler(eh, RetryConfig{MaxAttempts: 5, InitialBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := rh.HandleEvent(ctx, &Event{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry did not observe context, took %v", elapsed)
+	}
+
+	var rerr *RetryError
+	if errors.As(err, &rerr) || eh.calls != 1 {
+		t.Errorf("expected a single attempt, got calls=%d err=%v", eh.calls, err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cfg := RetryConfig{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     3,
+	}
+	cfg.setDefaults()
+
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := cfg.backoff(i + 1); got != w {
+			t.Errorf("retry %d: want=%v got=%v", i+1, w, got)
+		}
+	}
+
+	cfg.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := cfg.backoff(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("jittered backoff out of range: %v", got)
+		}
+	}
+}
+
+func TestEventRouterRetry(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+	er.ErrorPolicy = ErrorPolicyFailIfAny
+	er.Use(Retry(RetryConfig{InitialBackoff: time.Microsecond}))
+
+	eh := &flakyHandler{failures: 2, err: errors.New("transient")}
+	er.Mount(eh)
+
+	if err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if eh.calls != 3 {
+		t.Errorf("unexpected calls: want=3 got=%d", eh.calls)
+	}
+}
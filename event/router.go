@@ -2,6 +2,13 @@ package event
 
 import (
 	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -9,72 +16,581 @@ import (
 func NewEventRouter(logger *zap.Logger) *EventRouter {
 	return &EventRouter{
 		Logger:          logger,
-		typedHandlers:   make(map[EventType][]EventHandler),
-		untypedHandlers: make([]EventHandler, 0),
+		Metrics:         new(expvar.Map).Init(),
+		typedHandlers:   make(map[EventType][]*Route),
+		untypedHandlers: make([]*Route, 0),
 	}
 }
 
 type EventRouter struct {
 	*zap.Logger
-	typedHandlers   map[EventType][]EventHandler
-	untypedHandlers []EventHandler
+
+	// Source, if set, is stamped onto events that do not yet name one
+	Source string
+
+	// Concurrency enables parallel dispatch of each event to at most this
+	// many handlers at once; a negative value is unbounded. Zero or one
+	// dispatches sequentially. Handlers dispatched in parallel share the
+	// event and so must not modify it.
+	Concurrency int
+
+	// ErrorPolicy defaults to ErrorPolicyIgnore, which never fails
+	ErrorPolicy ErrorPolicy
+
+	// HandlerTimeout, if set, bounds the context passed to each handler.
+	HandlerTimeout time.Duration
+
+	// Metrics counts events and handler calls. It is not published to
+	// expvar by the router itself.
+	Metrics *expvar.Map
+
+	// mu guards the handler tables below, which may be changed by Mount
+	// and Unmount while events are being handled
+	mu sync.RWMutex
+
+	typedHandlers   map[EventType][]*Route
+	untypedHandlers []*Route
+
+	// patternHandlers is kept sorted from most to least specific pattern,
+	// see pattern.go
+	patternHandlers []patternRoute
+
+	middlewares []Middleware
+}
+
+// lastRouteID numbers routes across every router in the process, so that
+// route IDs remain unique when routers share an idempotency store or a
+// metrics map
+var lastRouteID int64
+
+// Route is a handler mounted on an EventRouter, see Mount and Unmount.
+type Route struct {
+	// id identifies the Mount call so that a handler matching an event
+	// several ways is still only called once
+	id        int
+	name      string
+	handler   EventHandler
+	types     []EventType
+	predicate Predicate
+	priority  int
+}
+
+// ID identifies the route within the process. IDs are assigned in mount
+// order, so are stable across restarts for as long as handlers are mounted
+// in the same order.
+func (rt *Route) ID() int {
+	return rt.id
+}
+
+func (rt *Route) Handler() EventHandler {
+	return rt.handler
+}
+
+// Types returns the types and patterns the handler was mounted for; nil
+// means every type.
+func (rt *Route) Types() []EventType {
+	return rt.types
+}
+
+// Name returns the name given by WithName, or else the name of the
+// handler's type.
+func (rt *Route) Name() string {
+	if rt.name != "" {
+		return rt.name
+	}
+	return handlerName(rt.handler)
+}
+
+func (rt *Route) Priority() int {
+	return rt.priority
+}
+
+type mountOptions struct {
+	name      string
+	predicate Predicate
+	priority  int
+}
+
+type MountOption func(*mountOptions)
+
+// WithPredicate only passes events to the mounted handler if p returns
+// true, e.g. WithPredicate(MustParseFilter(`region == "EU"`)).
+func WithPredicate(p Predicate) MountOption {
+	return func(o *mountOptions) {
+		o.predicate = p
+	}
 }
 
-func (h *EventRouter) Mount(eh EventHandler) {
-	types := eh.Handles()
+// WithName names the mounted handler in logs, dead letters and idempotency
+// keys. Handlers of the same type should be given distinct names to keep
+// their idempotency keys apart.
+func WithName(name string) MountOption {
+	return func(o *mountOptions) {
+		o.name = name
+	}
+}
+
+// WithPriority orders the mounted handler relative to others matching the
+// same event: higher priorities run first, and handlers of equal priority
+// (zero by default) run in the usual dispatch order. For example, a
+// validating handler mounted with a high priority may stop propagation
+// before others run, and an audit handler may be given a negative
+// priority to run last.
+func WithPriority(n int) MountOption {
+	return func(o *mountOptions) {
+		o.priority = n
+	}
+}
+
+type patternRoute struct {
+	*Route
+	pattern     EventType
+	specificity patternSpecificity
+}
+
+// Mount routes events to eh based on eh.Handles(): nil routes every event,
+// otherwise each returned type is matched exactly or, if it contains
+// wildcards, as a pattern. Handlers run in priority order (see
+// WithPriority), then in the order: catch-all handlers, exact matches,
+// then pattern matches from most to least specific pattern, each in mount
+// order.
+//
+// Mount may be called while events are being handled; the handler
+// receives events dispatched after Mount returns.
+func (h *EventRouter) Mount(eh EventHandler, opts ...MountOption) *Route {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.mount(eh, opts...)
+}
 
-	if types == nil {
-		h.untypedHandlers = append(h.untypedHandlers, eh)
-		return
+func (h *EventRouter) mount(eh EventHandler, opts ...MountOption) *Route {
+	var o mountOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	for _, typ := range types {
-		_, ok := h.typedHandlers[typ]
-		if !ok {
-			h.typedHandlers[typ] = make([]EventHandler, 0)
+	rt := &Route{
+		id:        int(atomic.AddInt64(&lastRouteID, 1)),
+		name:      o.name,
+		handler:   eh,
+		types:     eh.Handles(),
+		predicate: o.predicate,
+		priority:  o.priority,
+	}
+
+	if rt.types == nil {
+		h.untypedHandlers = append(h.untypedHandlers, rt)
+		return rt
+	}
+
+	for _, typ := range rt.types {
+		if IsEventTypePattern(typ) {
+			h.patternHandlers = append(h.patternHandlers, patternRoute{
+				Route:       rt,
+				pattern:     typ,
+				specificity: specificityOf(typ),
+			})
+			continue
 		}
-		h.typedHandlers[typ] = append(h.typedHandlers[typ], eh)
+
+		h.typedHandlers[typ] = append(h.typedHandlers[typ], rt)
 	}
+
+	sort.SliceStable(h.patternHandlers, func(i, j int) bool {
+		return h.patternHandlers[i].specificity.moreSpecificThan(h.patternHandlers[j].specificity)
+	})
+
+	return rt
 }
 
-//NOTE(bcwaldon): explicitly does NOT handle errors (other than logging) since it is unclear
-// what the general behavior should be when a portion of event handlers fail. This may change
-// in the future.
+// Unmount removes a route returned by Mount, reporting whether it was
+// still mounted. Dispatches already in progress may still call the
+// handler.
+func (h *EventRouter) Unmount(rt *Route) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.unmount(rt)
+}
+
+func (h *EventRouter) unmount(rt *Route) bool {
+	found := false
+
+	keep := func(routes []*Route) []*Route {
+		out := routes[:0]
+		for _, r := range routes {
+			if r == rt {
+				found = true
+				continue
+			}
+			out = append(out, r)
+		}
+		return out
+	}
+
+	h.untypedHandlers = keep(h.untypedHandlers)
+	for typ, routes := range h.typedHandlers {
+		routes = keep(routes)
+		if len(routes) == 0 {
+			delete(h.typedHandlers, typ)
+		} else {
+			h.typedHandlers[typ] = routes
+		}
+	}
+
+	patterns := make([]patternRoute, 0, len(h.patternHandlers))
+	for _, pr := range h.patternHandlers {
+		if pr.Route == rt {
+			found = true
+			continue
+		}
+		patterns = append(patterns, pr)
+	}
+	h.patternHandlers = patterns
+
+	return found
+}
+
+// Replace atomically unmounts old and mounts eh in its place, so that no
+// event is dispatched to both or to neither. The returned route is mounted
+// even if old was not.
+func (h *EventRouter) Replace(old *Route, eh EventHandler, opts ...MountOption) *Route {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unmount(old)
+	return h.mount(eh, opts...)
+}
+
+// Use wraps every handler mounted on the router, whether before or after
+// the call to Use, with the given middleware. Middleware is applied in the
+// order given, the first being outermost.
+func (h *EventRouter) Use(mws ...Middleware) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.middlewares = append(h.middlewares, mws...)
+}
+
+// AnyEventType lists catch-all handlers in the output of Routes.
+const AnyEventType EventType = "#"
+
+// Routes lists the mounted routes per type or pattern, each in dispatch
+// order. Handlers mounted for every type are listed under AnyEventType.
+func (h *EventRouter) Routes() map[EventType][]*Route {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	out := make(map[EventType][]*Route)
+	if len(h.untypedHandlers) > 0 {
+		out[AnyEventType] = append(out[AnyEventType], h.untypedHandlers...)
+	}
+	for typ, routes := range h.typedHandlers {
+		out[typ] = append(out[typ], routes...)
+	}
+	for _, pr := range h.patternHandlers {
+		out[pr.pattern] = append(out[pr.pattern], pr.Route)
+	}
+	return out
+}
+
+// routesFor returns a snapshot of the routes matching typ, in dispatch
+// order and without duplicates. The returned routes are copies whose
+// handlers are wrapped by any middleware.
+func (h *EventRouter) routesFor(typ EventType) []*Route {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	routes := make([]*Route, 0)
+	seen := make(map[int]bool)
+
+	add := func(rt *Route) {
+		if seen[rt.id] {
+			return
+		}
+		seen[rt.id] = true
+		routes = append(routes, h.wrap(rt))
+	}
+
+	for _, rt := range h.untypedHandlers {
+		add(rt)
+	}
+	for _, rt := range h.typedHandlers[typ] {
+		add(rt)
+	}
+	for _, pr := range h.patternHandlers {
+		if MatchEventType(pr.pattern, typ) {
+			add(pr.Route)
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].priority > routes[j].priority
+	})
+
+	return routes
+}
+
+// wrap returns a copy of rt whose handler is wrapped by any middleware. The
+// caller must hold h.mu.
+func (h *EventRouter) wrap(rt *Route) *Route {
+	if len(h.middlewares) == 0 {
+		return rt
+	}
+	wrapped := *rt
+	wrapped.handler = Chain(rt.handler, h.middlewares...)
+	return &wrapped
+}
+
+// handlersFor returns the routes for ev in dispatch order, skipping those
+// whose predicate rejects it.
+func (h *EventRouter) handlersFor(ev *Event, logger *zap.Logger) []*Route {
+	routes := make([]*Route, 0)
+	for _, rt := range h.routesFor(ev.Type) {
+		if rt.predicate != nil && !rt.predicate(ev) {
+			h.Metrics.Add("handlers_skipped", 1)
+			logger.Debug("skipped event handler", zap.String("handler", rt.Name()))
+			continue
+		}
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
+// handlerName identifies a handler in logs and errors, preferring a
+// Name() method when the handler provides one.
+func handlerName(eh EventHandler) string {
+	if n, ok := eh.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", eh)
+}
+
+type routeKey struct{}
+
+// RouteFromContext returns the route being dispatched to when called from
+// within a handler mounted on an EventRouter, and nil otherwise.
+func RouteFromContext(ctx context.Context) *Route {
+	rt, _ := ctx.Value(routeKey{}).(*Route)
+	return rt
+}
+
+// routeScope qualifies name with the ID of the route being dispatched to,
+// if any, so that handlers of the same type mounted separately are told
+// apart, e.g. in metrics.
+func routeScope(ctx context.Context, name string) string {
+	if rt := RouteFromContext(ctx); rt != nil {
+		return fmt.Sprintf("%s#%d", name, rt.id)
+	}
+	return name
+}
+
+// HandleEvent dispatches ev to every matching handler. Handler failures are
+// always logged, and reported to the caller according to ErrorPolicy. A
+// handler may end dispatch early by returning ErrStopPropagation.
 func (h *EventRouter) HandleEvent(ctx context.Context, ev *Event) error {
-	handlers := make([]EventHandler, 0)
-	handlers = append(handlers, h.untypedHandlers...)
+	if h.Source != "" && ev.Source == "" {
+		ev.Source = h.Source
+	}
 
 	logger := h.Logger.With(zap.String("type", string(ev.Type)))
 
-	typed, ok := h.typedHandlers[ev.Type]
-	if ok {
-		handlers = append(handlers, typed...)
-	}
+	h.Metrics.Add("events", 1)
+
+	routes := h.handlersFor(ev, logger)
 
-	if len(handlers) == 0 {
+	if len(routes) == 0 {
+		h.Metrics.Add("events_unhandled", 1)
 		logger.Debug("no handlers for event")
 		return nil
 	}
 
-	for _, eh := range handlers {
-		if err := eh.HandleEvent(ctx, ev); err != nil {
-			logger.Error("event handler failed", zap.Error(err))
+	errs, first := h.dispatch(ctx, ev, routes)
+
+	var failed []*HandlerError
+	var firstErr *HandlerError
+	for i, err := range errs {
+		if errors.Is(err, ErrStopPropagation) {
+			logger.Debug("event propagation stopped", zap.String("handler", routes[i].Name()))
+		}
+		if !isHandlerFailure(err) {
+			continue
+		}
+
+		he := &HandlerError{Handler: routes[i].Name(), Err: err}
+		h.Metrics.Add("handler_errors", 1)
+		logger.Error("event handler failed", zap.String("handler", he.Handler), zap.Error(err))
+
+		failed = append(failed, he)
+		if i == first {
+			firstErr = he
 		}
 	}
 
 	logger.Debug("handled event")
 
+	switch h.ErrorPolicy {
+	case ErrorPolicyFailFast:
+		if firstErr == nil && len(failed) > 0 {
+			firstErr = failed[0]
+		}
+		if firstErr != nil {
+			return &HandlerErrors{Errors: []*HandlerError{firstErr}}
+		}
+	case ErrorPolicyFailIfAny:
+		if len(failed) > 0 {
+			return &HandlerErrors{Errors: failed}
+		}
+	case ErrorPolicyFailIfAll:
+		if len(failed) == len(routes) {
+			return &HandlerErrors{Errors: failed}
+		}
+	}
+
 	return nil
 }
 
+// isHandlerFailure reports whether a handler's error counts as a failure;
+// a bare ErrStopPropagation does not.
+func isHandlerFailure(err error) bool {
+	return err != nil && err != ErrStopPropagation
+}
+
+// dispatch calls each handler, returning their errors in handler order
+// regardless of the order in which they complete. Handlers are called in
+// waves of equal priority, in parallel if Concurrency allows, and no
+// further waves are started once a handler stops propagation. Under
+// ErrorPolicyFailFast dispatch stops at the first failure, whose index is
+// returned as first; otherwise first is -1.
+func (h *EventRouter) dispatch(ctx context.Context, ev *Event, routes []*Route) (errs []error, first int) {
+	errs = make([]error, len(routes))
+	first = -1
+	parallel := h.Concurrency != 0 && h.Concurrency != 1
+
+	for start := 0; start < len(routes); {
+		end := start + 1
+		for parallel && end < len(routes) && routes[end].priority == routes[start].priority {
+			end++
+		}
+
+		var wfirst int
+		if end-start == 1 {
+			errs[start] = h.call(ctx, routes[start], ev)
+			wfirst = -1
+			if isHandlerFailure(errs[start]) {
+				wfirst = 0
+			}
+		} else {
+			wfirst = h.dispatchParallel(ctx, ev, routes[start:end], errs[start:end])
+		}
+
+		if wfirst >= 0 && h.ErrorPolicy == ErrorPolicyFailFast {
+			return errs, start + wfirst
+		}
+		for _, err := range errs[start:end] {
+			if errors.Is(err, ErrStopPropagation) {
+				return errs, first
+			}
+		}
+
+		start = end
+	}
+
+	return errs, first
+}
+
+// dispatchParallel calls routes concurrently, bounded by Concurrency,
+// storing their errors in errs. Under ErrorPolicyFailFast the first failure
+// cancels the remaining handlers, and its index is returned; otherwise -1
+// is returned.
+func (h *EventRouter) dispatchParallel(ctx context.Context, ev *Event, routes []*Route, errs []error) int {
+	first := -1
+	failFast := h.ErrorPolicy == ErrorPolicyFailFast
+
+	dctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var sem chan struct{}
+	if h.Concurrency > 1 {
+		sem = make(chan struct{}, h.Concurrency)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, rt := range routes {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-dctx.Done():
+			}
+		}
+		if dctx.Err() != nil {
+			// handlers never started due to fail-fast are not failures
+			// of their own
+			if ctx.Err() != nil {
+				errs[i] = ctx.Err()
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, rt *Route) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			err := h.call(dctx, rt, ev)
+			errs[i] = err
+			if isHandlerFailure(err) && failFast {
+				mu.Lock()
+				if first < 0 {
+					first = i
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(i, rt)
+	}
+	wg.Wait()
+
+	return first
+}
+
+// HandleEventRoute passes ev to the handler mounted as rt alone, applying
+// middleware as HandleEvent would, and returns the handler's error as-is.
+// The route's types and predicate are not consulted, e.g. when replaying
+// a dead letter to the handler that failed it.
+func (h *EventRouter) HandleEventRoute(ctx context.Context, rt *Route, ev *Event) error {
+	h.mu.RLock()
+	rt = h.wrap(rt)
+	h.mu.RUnlock()
+
+	return h.call(ctx, rt, ev)
+}
+
+func (h *EventRouter) call(ctx context.Context, rt *Route, ev *Event) error {
+	h.Metrics.Add("handler_calls", 1)
+
+	if h.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.HandlerTimeout)
+		defer cancel()
+	}
+
+	return rt.handler.HandleEvent(context.WithValue(ctx, routeKey{}, rt), ev)
+}
+
 func (h *EventRouter) Handles() []EventType {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
 	if len(h.untypedHandlers) > 0 {
 		return nil
 	}
 
-	types := make([]EventType, 0, len(h.typedHandlers))
+	types := make([]EventType, 0, len(h.typedHandlers)+len(h.patternHandlers))
 	for k, _ := range h.typedHandlers {
 		types = append(types, k)
 	}
+	for _, pr := range h.patternHandlers {
+		types = append(types, pr.pattern)
+	}
 	return types
 }
@@ -2,11 +2,18 @@ package event
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type fixtureHandler struct {
@@ -138,3 +145,633 @@ func TestEventRouterNoErrorPropagation(t *testing.T) {
 		t.Errorf("events did not route properly: want=%+v got=%+v", want, got)
 	}
 }
+
+func TestEventRouterSource(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	er := NewEventRouter(logger)
+	er.Source = "example_source"
+
+	eh := &fixtureHandler{types: nil, err: nil}
+	er.Mount(eh)
+
+	ev1 := Event{Type: EventType("test1")}
+	ev2 := Event{Type: EventType("test2"), Source: "other_source"}
+
+	if err := er.HandleEvent(context.Background(), &ev1); err != nil {
+		t.Errorf("received unexpected error: %v", err)
+	}
+	if err := er.HandleEvent(context.Background(), &ev2); err != nil {
+		t.Errorf("received unexpected error: %v", err)
+	}
+
+	if got := eh.events[0].Source; got != "example_source" {
+		t.Errorf("expected source to be stamped: got=%q", got)
+	}
+	if got := eh.events[1].Source; got != "other_source" {
+		t.Errorf("expected existing source to be kept: got=%q", got)
+	}
+}
+
+func TestMatchEventType(t *testing.T) {
+	tests := []struct {
+		pattern EventType
+		typ     EventType
+		want    bool
+	}{
+		{"dataset.ingest.completed", "dataset.ingest.completed", true},
+		{"dataset.ingest.completed", "dataset.ingest.failed", false},
+		{"dataset.ingest.*", "dataset.ingest.completed", true},
+		{"dataset.ingest.*", "dataset.ingest", false},
+		{"dataset.ingest.*", "dataset.ingest.completed.late", false},
+		{"dataset.*.completed", "dataset.ingest.completed", true},
+		{"dataset.*.completed", "dataset.ingest.failed", false},
+		{"*", "dataset", true},
+		{"*", "dataset.ingest", false},
+		{"dataset.#", "dataset", true},
+		{"dataset.#", "dataset.ingest", true},
+		{"dataset.#", "dataset.ingest.completed", true},
+		{"dataset.#", "datasets.ingest", false},
+		{"#", "dataset.ingest.completed", true},
+		{"#.completed", "dataset.ingest.completed", true},
+		{"#.completed", "completed", true},
+		{"#.completed", "dataset.ingest.failed", false},
+		{"dataset.#.completed", "dataset.completed", true},
+		{"dataset.#.completed", "dataset.a.b.completed", true},
+		{"dataset.#.*", "dataset", false},
+		{"dataset.#.*", "dataset.ingest", true},
+		{"dataset.ingest*", "dataset.ingest.completed", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchEventType(tt.pattern, tt.typ); got != tt.want {
+			t.Errorf("MatchEventType(%q, %q): want=%v got=%v", tt.pattern, tt.typ, tt.want, got)
+		}
+	}
+}
+
+type orderHandler struct {
+	name  string
+	types []EventType
+	order *[]string
+}
+
+// orderMu guards the order slices of orderHandlers dispatched in parallel
+var orderMu sync.Mutex
+
+func (h *orderHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	orderMu.Lock()
+	defer orderMu.Unlock()
+	*h.order = append(*h.order, h.name)
+	return nil
+}
+
+func (h *orderHandler) Handles() []EventType {
+	return h.types
+}
+
+func TestEventRouterPatterns(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	var order []string
+	mount := func(er *EventRouter, name string, types ...EventType) {
+		er.Mount(&orderHandler{name: name, types: types, order: &order})
+	}
+
+	er := NewEventRouter(logger)
+	mount(er, "all-hash", "#")
+	mount(er, "dataset-hash", "dataset.#")
+	mount(er, "ingest-star", "dataset.ingest.*")
+	mount(er, "exact", "dataset.ingest.completed")
+	mount(er, "untyped")
+	mount(er, "star-completed", "dataset.*.completed")
+	mount(er, "both", "dataset.ingest.completed", "dataset.#")
+	mount(er, "other", "other.#")
+
+	tests := []struct {
+		typ  EventType
+		want []string
+	}{
+		{
+			typ:  "dataset.ingest.completed",
+			want: []string{"untyped", "exact", "both", "ingest-star", "star-completed", "dataset-hash", "all-hash"},
+		},
+		{
+			typ:  "dataset.ingest.failed",
+			want: []string{"untyped", "ingest-star", "dataset-hash", "both", "all-hash"},
+		},
+		{
+			typ:  "dataset",
+			want: []string{"untyped", "dataset-hash", "both", "all-hash"},
+		},
+		{
+			typ:  "unrelated",
+			want: []string{"untyped", "all-hash"},
+		},
+	}
+
+	for _, tt := range tests {
+		order = nil
+		if err := er.HandleEvent(context.Background(), &Event{Type: tt.typ}); err != nil {
+			t.Errorf("received unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(tt.want, order) {
+			t.Errorf("type=%s: unexpected dispatch order: want=%v got=%v", tt.typ, tt.want, order)
+		}
+	}
+}
+
+func TestEventRouterPatternHandles(t *testing.T) {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	er := NewEventRouter(logger)
+	er.Mount(&fixtureHandler{types: []EventType{"dataset.#"}})
+	er.Mount(&fixtureHandler{types: []EventType{"test"}})
+
+	got := er.Handles()
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+	want := []EventType{"dataset.#", "test"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected Handles: want=%v got=%v", want, got)
+	}
+}
+
+func TestEventRouterPredicate(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	er := NewEventRouter(zap.New(core))
+
+	eu := &fixtureHandler{}
+	er.Mount(eu, WithPredicate(MustParseFilter(`region == "EU"`)))
+
+	urgent := &fixtureHandler{types: []EventType{"test"}}
+	er.Mount(urgent, WithPredicate(func(ev *Event) bool {
+		v, err := ev.IntField("priority")
+		return err == nil && v > 5
+	}))
+
+	ev1 := Event{Type: EventType("test"), Fields: []EventField{Field("region", "EU"), Field("priority", 1)}}
+	ev2 := Event{Type: EventType("test"), Fields: []EventField{Field("region", "US"), Field("priority", 9)}}
+
+	for _, ev := range []Event{ev1, ev2} {
+		if err := er.HandleEvent(context.Background(), &ev); err != nil {
+			t.Errorf("received unexpected error: %v", err)
+		}
+	}
+
+	if want := []Event{ev1}; !reflect.DeepEqual(want, eu.events) {
+		t.Errorf("events did not route properly to eu: want=%+v got=%+v", want, eu.events)
+	}
+	if want := []Event{ev2}; !reflect.DeepEqual(want, urgent.events) {
+		t.Errorf("events did not route properly to urgent: want=%+v got=%+v", want, urgent.events)
+	}
+
+	if got := logs.FilterMessage("skipped event handler").Len(); got != 2 {
+		t.Errorf("expected 2 skip log entries, got %d", got)
+	}
+	if got := er.Metrics.Get("handlers_skipped").String(); got != "2" {
+		t.Errorf("unexpected handlers_skipped metric: want=2 got=%s", got)
+	}
+	if got := er.Metrics.Get("handler_calls").String(); got != "2" {
+		t.Errorf("unexpected handler_calls metric: want=2 got=%s", got)
+	}
+}
+
+type slowHandler struct {
+	name  string
+	delay time.Duration
+	err   error
+
+	active  *int32
+	maxSeen *int32
+}
+
+func (h *slowHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	n := atomic.AddInt32(h.active, 1)
+	defer atomic.AddInt32(h.active, -1)
+	for {
+		max := atomic.LoadInt32(h.maxSeen)
+		if n <= max || atomic.CompareAndSwapInt32(h.maxSeen, max, n) {
+			break
+		}
+	}
+
+	select {
+	case <-time.After(h.delay):
+		return h.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *slowHandler) Handles() []EventType {
+	return nil
+}
+
+func (h *slowHandler) Name() string {
+	return h.name
+}
+
+func TestEventRouterConcurrency(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		wantMax     int32
+	}{
+		{concurrency: 0, wantMax: 1},
+		{concurrency: 1, wantMax: 1},
+		{concurrency: 2, wantMax: 2},
+		{concurrency: -1, wantMax: 4},
+	}
+
+	for _, tt := range tests {
+		core, logs := observer.New(zap.ErrorLevel)
+		er := NewEventRouter(zap.New(core))
+		er.Concurrency = tt.concurrency
+
+		var active, maxSeen int32
+		for i := 0; i < 4; i++ {
+			er.Mount(&slowHandler{
+				name:    fmt.Sprintf("handler-%d", i),
+				delay:   time.Duration(4-i) * 10 * time.Millisecond,
+				err:     fmt.Errorf("failed-%d", i),
+				active:  &active,
+				maxSeen: &maxSeen,
+			})
+		}
+
+		if err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")}); err != nil {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		if maxSeen != tt.wantMax {
+			t.Errorf("concurrency=%d: unexpected max concurrent handlers: want=%d got=%d", tt.concurrency, tt.wantMax, maxSeen)
+		}
+
+		// errors are reported in handler order regardless of completion order
+		var got []string
+		for _, entry := range logs.All() {
+			got = append(got, entry.ContextMap()["handler"].(string))
+		}
+		want := []string{"handler-0", "handler-1", "handler-2", "handler-3"}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("concurrency=%d: unexpected error order: want=%v got=%v", tt.concurrency, want, got)
+		}
+	}
+}
+
+func TestEventRouterHandlerTimeout(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	er := NewEventRouter(zap.New(core))
+	er.Concurrency = 2
+	er.HandlerTimeout = 10 * time.Millisecond
+
+	var active, maxSeen int32
+	er.Mount(&slowHandler{name: "slow", delay: time.Second, active: &active, maxSeen: &maxSeen})
+	er.Mount(&slowHandler{name: "fast", delay: time.Millisecond, active: &active, maxSeen: &maxSeen})
+
+	start := time.Now()
+	if err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")}); err != nil {
+		t.Errorf("received unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("handler timeout not applied, took %v", elapsed)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].ContextMap()["handler"] != "slow" {
+		t.Fatalf("expected a single failure of the slow handler, got %+v", entries)
+	}
+	if got := entries[0].ContextMap()["error"]; got != context.DeadlineExceeded.Error() {
+		t.Errorf("unexpected error: %v", got)
+	}
+}
+
+func TestEventRouterConcurrencyCanceled(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+	er.Concurrency = 2
+
+	var active, maxSeen int32
+	er.Mount(&slowHandler{name: "a", delay: time.Second, active: &active, maxSeen: &maxSeen})
+	er.Mount(&slowHandler{name: "b", delay: time.Second, active: &active, maxSeen: &maxSeen})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ev := &Event{Type: EventType("test")}
+	errs, _ := er.dispatch(ctx, ev, er.handlersFor(ev, zap.NewNop()))
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler %d: expected context.Canceled, got %v", i, err)
+		}
+	}
+}
+
+func TestEventRouterErrorPolicy(t *testing.T) {
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+
+	tests := []struct {
+		policy      ErrorPolicy
+		concurrency int
+		errs        []error
+		want        []error
+		wantCalls   int
+	}{
+		{policy: ErrorPolicyIgnore, errs: []error{errA, errB}, want: nil, wantCalls: 2},
+		{policy: ErrorPolicyFailFast, errs: []error{errA, errB}, want: []error{errA}, wantCalls: 1},
+		{policy: ErrorPolicyFailFast, errs: []error{nil, nil}, want: nil, wantCalls: 2},
+		{policy: ErrorPolicyFailIfAny, errs: []error{errA, nil, errB}, want: []error{errA, errB}, wantCalls: 3},
+		{policy: ErrorPolicyFailIfAny, errs: []error{nil, nil}, want: nil, wantCalls: 2},
+		{policy: ErrorPolicyFailIfAll, errs: []error{errA, nil}, want: nil, wantCalls: 2},
+		{policy: ErrorPolicyFailIfAll, errs: []error{errA, errB}, want: []error{errA, errB}, wantCalls: 2},
+		{policy: ErrorPolicyFailIfAny, concurrency: 2, errs: []error{errA, nil, errB}, want: []error{errA, errB}, wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		er := NewEventRouter(zap.NewNop())
+		er.ErrorPolicy = tt.policy
+		er.Concurrency = tt.concurrency
+
+		var handlers []*fixtureHandler
+		for _, err := range tt.errs {
+			eh := &fixtureHandler{err: err}
+			handlers = append(handlers, eh)
+			er.Mount(eh)
+		}
+
+		err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+		var got []error
+		if err != nil {
+			var herr *HandlerErrors
+			if !errors.As(err, &herr) {
+				t.Fatalf("policy=%v: expected *HandlerErrors, got %v", tt.policy, err)
+			}
+			for _, he := range herr.Errors {
+				if he.Handler != "*event.fixtureHandler" {
+					t.Errorf("policy=%v: unexpected handler name %q", tt.policy, he.Handler)
+				}
+				got = append(got, he.Err)
+			}
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("policy=%v concurrency=%d: want=%v got=%v", tt.policy, tt.concurrency, tt.want, got)
+		}
+
+		calls := 0
+		for _, eh := range handlers {
+			calls += len(eh.events)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("policy=%v: unexpected handler calls: want=%d got=%d", tt.policy, tt.wantCalls, calls)
+		}
+	}
+}
+
+func TestEventRouterFailFastParallel(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+	er.ErrorPolicy = ErrorPolicyFailFast
+	er.Concurrency = -1
+
+	errA := errors.New("a failed")
+
+	var active, maxSeen int32
+	er.Mount(&slowHandler{name: "slow", delay: time.Second, active: &active, maxSeen: &maxSeen})
+	er.Mount(&slowHandler{name: "failing", delay: time.Millisecond, err: errA, active: &active, maxSeen: &maxSeen})
+
+	start := time.Now()
+	err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("slow handler not canceled, took %v", elapsed)
+	}
+
+	var herr *HandlerErrors
+	if !errors.As(err, &herr) || len(herr.Errors) != 1 || herr.Errors[0].Handler != "failing" {
+		t.Fatalf("expected single failure of failing handler, got %v", err)
+	}
+	if !errors.Is(err, errA) {
+		t.Errorf("expected error to match errA")
+	}
+}
+
+func TestParseErrorPolicy(t *testing.T) {
+	for _, p := range []ErrorPolicy{ErrorPolicyIgnore, ErrorPolicyFailFast, ErrorPolicyFailIfAny, ErrorPolicyFailIfAll} {
+		got, err := ParseErrorPolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseErrorPolicy(%q): got=%v err=%v", p.String(), got, err)
+		}
+	}
+
+	if got, err := ParseErrorPolicy(""); err != nil || got != ErrorPolicyIgnore {
+		t.Errorf("expected empty policy to select ignore: got=%v err=%v", got, err)
+	}
+	if _, err := ParseErrorPolicy("retry"); err == nil {
+		t.Errorf("expected error for unknown policy")
+	}
+}
+
+func TestEventRouterUnmount(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+
+	eh1 := &fixtureHandler{types: []EventType{"test", "dataset.#"}}
+	rt1 := er.Mount(eh1)
+	eh2 := &fixtureHandler{}
+	rt2 := er.Mount(eh2)
+
+	ev1 := Event{Type: EventType("test")}
+	er.HandleEvent(context.Background(), &ev1)
+
+	if !er.Unmount(rt1) {
+		t.Errorf("expected route to be unmounted")
+	}
+	if er.Unmount(rt1) {
+		t.Errorf("expected second unmount to report false")
+	}
+
+	ev2 := Event{Type: EventType("dataset.ingest")}
+	er.HandleEvent(context.Background(), &ev2)
+
+	if want := []Event{ev1}; !reflect.DeepEqual(want, eh1.events) {
+		t.Errorf("unexpected events for unmounted handler: want=%+v got=%+v", want, eh1.events)
+	}
+	if want := []Event{ev1, ev2}; !reflect.DeepEqual(want, eh2.events) {
+		t.Errorf("unexpected events for mounted handler: want=%+v got=%+v", want, eh2.events)
+	}
+
+	er.Unmount(rt2)
+	if got := er.Routes(); len(got) != 0 {
+		t.Errorf("expected no routes, got %v", got)
+	}
+	if got := er.Handles(); len(got) != 0 {
+		t.Errorf("expected no handled types, got %v", got)
+	}
+}
+
+func TestEventRouterReplace(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+
+	old := &fixtureHandler{types: []EventType{"test"}}
+	rt := er.Mount(old)
+
+	eh := &fixtureHandler{types: []EventType{"test"}}
+	nrt := er.Replace(rt, eh)
+
+	if nrt.Handler() != eh {
+		t.Errorf("unexpected handler on replacement route")
+	}
+
+	er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+	if len(old.events) != 0 || len(eh.events) != 1 {
+		t.Errorf("unexpected dispatch after replace: old=%d new=%d", len(old.events), len(eh.events))
+	}
+}
+
+func TestEventRouterRoutes(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+
+	rt1 := er.Mount(&fixtureHandler{types: []EventType{"test", "dataset.*"}})
+	rt2 := er.Mount(&fixtureHandler{})
+	rt3 := er.Mount(&fixtureHandler{types: []EventType{"test"}})
+
+	want := map[EventType][]*Route{
+		"test":       {rt1, rt3},
+		"dataset.*":  {rt1},
+		AnyEventType: {rt2},
+	}
+	if got := er.Routes(); !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected routes: want=%v got=%v", want, got)
+	}
+
+	if got := rt1.Types(); !reflect.DeepEqual([]EventType{"test", "dataset.*"}, got) {
+		t.Errorf("unexpected route types: %v", got)
+	}
+	if got := rt2.Name(); got != "*event.fixtureHandler" {
+		t.Errorf("unexpected route name: %v", got)
+	}
+}
+
+func TestEventRouterConcurrentMount(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+	er.Mount(NewLogHandler(zap.NewNop(), LogHandlerConfig{}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			rt := er.Mount(NewLogHandler(zap.NewNop(), LogHandlerConfig{Types: []EventType{"test"}}))
+			er.Routes()
+			er.Unmount(rt)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+		er.Handles()
+	}
+	<-done
+}
+
+// TestEventRouterConcurrentRead exercises the field index under -race:
+// parallel handlers look up and marshal the same event.
+func TestEventRouterConcurrentRead(t *testing.T) {
+	er := NewEventRouter(zap.NewNop())
+	er.Concurrency = 2
+
+	reader := WrapHandler(&fixtureHandler{}, func(ctx context.Context, ev *Event) error {
+		for i := 0; i < 100; i++ {
+			if _, err := ev.FloatField(EventFieldKey(fmt.Sprintf("field_%d", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	marshaler := WrapHandler(&fixtureHandler{}, func(ctx context.Context, ev *Event) error {
+		_, err := json.Marshal(ev)
+		return err
+	})
+	er.Mount(reader)
+	er.Mount(marshaler)
+
+	for i := 0; i < 20; i++ {
+		if err := er.HandleEvent(context.Background(), largeEvent(100)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+type stopHandler struct {
+	orderHandler
+	err error
+}
+
+func (h *stopHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	h.orderHandler.HandleEvent(ctx, ev)
+	return h.err
+}
+
+func TestEventRouterPriority(t *testing.T) {
+	for _, concurrency := range []int{0, 2} {
+		var order []string
+		er := NewEventRouter(zap.NewNop())
+		er.Concurrency = concurrency
+
+		er.Mount(&orderHandler{name: "audit", order: &order}, WithPriority(-10))
+		er.Mount(&orderHandler{name: "untyped", order: &order})
+		er.Mount(&orderHandler{name: "typed", types: []EventType{"test"}, order: &order})
+		er.Mount(&orderHandler{name: "validate", types: []EventType{"test"}, order: &order}, WithPriority(10))
+
+		er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+		if concurrency > 1 {
+			// handlers of equal priority may complete in any order
+			sort.Strings(order[1:3])
+		}
+		want := []string{"validate", "typed", "untyped", "audit"}
+		if concurrency == 0 {
+			want = []string{"validate", "untyped", "typed", "audit"}
+		}
+		if !reflect.DeepEqual(want, order) {
+			t.Errorf("concurrency=%d: unexpected dispatch order: want=%v got=%v", concurrency, want, order)
+		}
+	}
+}
+
+func TestEventRouterStopPropagation(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	tests := []struct {
+		err     error
+		wantErr bool
+	}{
+		{err: ErrStopPropagation, wantErr: false},
+		{err: StopPropagation(errInvalid), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		for _, concurrency := range []int{0, -1} {
+			var order []string
+			er := NewEventRouter(zap.NewNop())
+			er.Concurrency = concurrency
+			er.ErrorPolicy = ErrorPolicyFailIfAny
+
+			er.Mount(&orderHandler{name: "later", order: &order})
+			er.Mount(&stopHandler{orderHandler: orderHandler{name: "validate", order: &order}, err: tt.err}, WithPriority(1))
+
+			err := er.HandleEvent(context.Background(), &Event{Type: EventType("test")})
+
+			if want := []string{"validate"}; !reflect.DeepEqual(want, order) {
+				t.Errorf("err=%v concurrency=%d: unexpected dispatch order: want=%v got=%v", tt.err, concurrency, want, order)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err=%v concurrency=%d: unexpected error: %v", tt.err, concurrency, err)
+			}
+			if tt.wantErr && !errors.Is(err, errInvalid) {
+				t.Errorf("expected error to match wrapped error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrSchemaNotFound = errors.New("no schema registered for event type")
+	ErrEventInvalid   = errors.New("event does not match schema")
+)
+
+// FieldType identifies the accessor used to validate a field, e.g. a
+// FieldTypeTime field must be readable via Event.TimeField.
+type FieldType string
+
+const (
+	FieldTypeAny         FieldType = "any"
+	FieldTypeString      FieldType = "string"
+	FieldTypeBool        FieldType = "bool"
+	FieldTypeInt         FieldType = "int"
+	FieldTypeFloat       FieldType = "float"
+	FieldTypeTime        FieldType = "time"
+	FieldTypeDuration    FieldType = "duration"
+	FieldTypeStringSlice FieldType = "[]string"
+	FieldTypeObject      FieldType = "object"
+)
+
+type FieldSchema struct {
+	Key      EventFieldKey
+	Type     FieldType
+	Required bool
+
+	// Allowed, if non-empty, restricts the field to one of the listed
+	// values. Values are compared after being read via the typed accessor,
+	// so numeric constraints must be listed as int64 or float64.
+	Allowed []interface{}
+}
+
+type Schema struct {
+	Type   EventType
+	Fields []FieldSchema
+
+	// AllowUnknownFields permits fields that are not declared above
+	AllowUnknownFields bool
+}
+
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		schemas: make(map[EventType]*Schema),
+	}
+}
+
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[EventType]*Schema
+}
+
+func (r *SchemaRegistry) Register(s *Schema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[s.Type] = s
+}
+
+func (r *SchemaRegistry) Lookup(typ EventType) (*Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schemas[typ]
+	return s, ok
+}
+
+// Validate checks ev against the schema registered for its type, returning
+// a *ValidationError describing every offending field.
+func (r *SchemaRegistry) Validate(ev *Event) error {
+	s, ok := r.Lookup(ev.Type)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, ev.Type)
+	}
+	return s.Validate(ev)
+}
+
+type FieldViolation struct {
+	Key    EventFieldKey
+	Reason string
+	Err    error
+}
+
+type ValidationError struct {
+	Type       EventType
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s: %s", v.Key, v.Reason)
+	}
+	return fmt.Sprintf("invalid %s event: %s", e.Type, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() error { return ErrEventInvalid }
+
+func (s *Schema) Validate(ev *Event) error {
+	verr := ValidationError{Type: ev.Type}
+
+	declared := make(map[EventFieldKey]bool, len(s.Fields))
+	for _, fs := range s.Fields {
+		declared[fs.Key] = true
+
+		val, err := readSchemaField(ev.Detailed(), fs)
+		if errors.Is(err, ErrFieldMissing) {
+			if fs.Required {
+				verr.Violations = append(verr.Violations, FieldViolation{Key: fs.Key, Reason: "required field missing", Err: err})
+			}
+			continue
+		}
+		if err != nil {
+			verr.Violations = append(verr.Violations, FieldViolation{Key: fs.Key, Reason: fmt.Sprintf("expected %s", fs.Type), Err: err})
+			continue
+		}
+
+		if len(fs.Allowed) > 0 && !containsValue(fs.Allowed, val) {
+			verr.Violations = append(verr.Violations, FieldViolation{Key: fs.Key, Reason: fmt.Sprintf("value %v not allowed", val), Err: ErrFieldUnexpectedValue})
+		}
+	}
+
+	if !s.AllowUnknownFields {
+		for _, ef := range ev.Fields {
+			if !declared[ef.Key] {
+				verr.Violations = append(verr.Violations, FieldViolation{Key: ef.Key, Reason: "unknown field"})
+				declared[ef.Key] = true
+			}
+		}
+	}
+
+	if len(verr.Violations) > 0 {
+		return &verr
+	}
+	return nil
+}
+
+func readSchemaField(ev DetailedEvent, fs FieldSchema) (interface{}, error) {
+	switch fs.Type {
+	case FieldTypeString:
+		return ev.StringField(fs.Key)
+	case FieldTypeBool:
+		return ev.BoolField(fs.Key)
+	case FieldTypeInt:
+		return ev.Int64Field(fs.Key)
+	case FieldTypeFloat:
+		return ev.FloatField(fs.Key)
+	case FieldTypeTime:
+		return ev.TimeField(fs.Key)
+	case FieldTypeDuration:
+		return ev.DurationField(fs.Key)
+	case FieldTypeStringSlice:
+		return ev.StringSliceField(fs.Key)
+	case FieldTypeObject:
+		var obj map[string]interface{}
+		err := ev.JSONField(fs.Key, &obj)
+		return obj, err
+	case FieldTypeAny, "":
+		return ev.Field(fs.Key)
+	}
+	return nil, fmt.Errorf("unknown schema field type %q", fs.Type)
+}
+
+func containsValue(allowed []interface{}, val interface{}) bool {
+	for _, a := range allowed {
+		if reflect.DeepEqual(a, val) {
+			return true
+		}
+	}
+	return false
+}
+
+type validationErrorKey struct{}
+
+// ValidationErrorFromContext returns the reason an event was rejected when
+// called from within the invalid-event handler of a validating handler,
+// and nil otherwise.
+func ValidationErrorFromContext(ctx context.Context) error {
+	err, _ := ctx.Value(validationErrorKey{}).(error)
+	return err
+}
+
+// NewValidatingHandler wraps next such that only events matching their
+// registered schema are passed through. Invalid events (including those
+// with no registered schema) are handed to invalid, e.g. a dead-letter
+// publisher, if it is non-nil; otherwise the validation error is returned.
+//
+// Both publishers mounted on an outbound router and an inbound router as a
+// whole (e.g. behind a PubSub adapter) may be wrapped.
+func NewValidatingHandler(reg *SchemaRegistry, next EventHandler, invalid EventHandler) EventHandler {
+	return &validatingHandler{
+		registry: reg,
+		next:     next,
+		invalid:  invalid,
+	}
+}
+
+type validatingHandler struct {
+	registry *SchemaRegistry
+	next     EventHandler
+	invalid  EventHandler
+}
+
+func (h *validatingHandler) HandleEvent(ctx context.Context, ev *Event) error {
+	if err := h.registry.Validate(ev); err != nil {
+		if h.invalid == nil {
+			return err
+		}
+		return h.invalid.HandleEvent(context.WithValue(ctx, validationErrorKey{}, err), ev)
+	}
+	return h.next.HandleEvent(ctx, ev)
+}
+
+func (h *validatingHandler) Handles() []EventType {
+	return h.next.Handles()
+}
@@ -0,0 +1,110 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func schemaFixture() *SchemaRegistry {
+	reg := NewSchemaRegistry()
+	reg.Register(&Schema{
+		Type: EventType("asset.scored"),
+		Fields: []FieldSchema{
+			{Key: "asset_id", Type: FieldTypeString, Required: true},
+			{Key: "region", Type: FieldTypeString, Allowed: []interface{}{"EU", "US"}},
+			{Key: "score", Type: FieldTypeFloat, Required: true},
+			{Key: "scored_at", Type: FieldTypeTime},
+		},
+	})
+	return reg
+}
+
+func TestSchemaValidate(t *testing.T) {
+	reg := schemaFixture()
+
+	valid := NewEvent(EventType("asset.scored"),
+		Field("asset_id", "a1"),
+		Field("region", "EU"),
+		Field("score", 0.5),
+	)
+	if err := reg.Validate(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := NewEvent(EventType("asset.scored"),
+		Field("region", "APAC"),
+		Field("score", "high"),
+		Field("scored_at", "yesterday"),
+		Field("extra", 1),
+	)
+
+	err := reg.Validate(invalid)
+	if !errors.Is(err, ErrEventInvalid) {
+		t.Fatalf("expected ErrEventInvalid, got %v", err)
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T", err)
+	}
+
+	want := []EventFieldKey{"asset_id", "region", "score", "scored_at", "extra"}
+	if len(verr.Violations) != len(want) {
+		t.Fatalf("unexpected violations: %v", verr)
+	}
+	for i, key := range want {
+		if verr.Violations[i].Key != key {
+			t.Errorf("unexpected violation %d: want=%s got=%s", i, key, verr.Violations[i].Key)
+		}
+	}
+
+	var ferr *FieldError
+	if !errors.As(verr.Violations[2].Err, &ferr) || ferr.Got != "string" || !errors.Is(ferr, ErrFieldIncorrectType) {
+		t.Errorf("expected detailed field error, got %v", verr.Violations[2].Err)
+	}
+
+	if err := reg.Validate(NewEvent(EventType("unknown"))); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+type invalidRecorder struct {
+	fixtureHandler
+	reasons []error
+}
+
+func (h *invalidRecorder) HandleEvent(ctx context.Context, ev *Event) error {
+	h.reasons = append(h.reasons, ValidationErrorFromContext(ctx))
+	return h.fixtureHandler.HandleEvent(ctx, ev)
+}
+
+func TestValidatingHandler(t *testing.T) {
+	reg := schemaFixture()
+
+	valid := NewEvent(EventType("asset.scored"), Field("asset_id", "a1"), Field("score", 1))
+	invalid := NewEvent(EventType("asset.scored"), Field("asset_id", "a1"))
+
+	next := &fixtureHandler{}
+	rejecting := NewValidatingHandler(reg, next, nil)
+
+	if err := rejecting.HandleEvent(context.Background(), valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := rejecting.HandleEvent(context.Background(), invalid); !errors.Is(err, ErrEventInvalid) {
+		t.Errorf("expected ErrEventInvalid, got %v", err)
+	}
+	if len(next.events) != 1 {
+		t.Errorf("expected only valid event to pass through, got %d", len(next.events))
+	}
+
+	dlq := &invalidRecorder{}
+	deadLettering := NewValidatingHandler(reg, &fixtureHandler{}, dlq)
+
+	if err := deadLettering.HandleEvent(context.Background(), invalid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(dlq.events) != 1 || !errors.Is(dlq.reasons[0], ErrEventInvalid) {
+		t.Errorf("expected invalid event to be dead-lettered with its reason, got %+v", dlq.reasons)
+	}
+}
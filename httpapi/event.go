@@ -0,0 +1,210 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/sustglobal/gost/event"
+)
+
+type EventFormat int
+
+const (
+	// EventFormatJSON is the native {"type": ..., "fields": [...]} encoding
+	EventFormatJSON EventFormat = iota
+	EventFormatCloudEventsStructured
+	EventFormatCloudEventsBinary
+)
+
+const cloudEventsHeaderPrefix = "Ce-"
+
+// NewEventHandler accepts events POSTed to path and passes them to eh.
+// CloudEvents binary mode requests are accepted alongside any payload with
+// a Content-Type registered via event.RegisterCodec. Payloads with any other
+// Content-Type are decoded with event.DefaultCodec; see ReadEvent.
+func NewEventHandler(path string, eh event.EventHandler, logger *zap.Logger) HandlerMounter {
+	return &eventHandler{
+		path:    path,
+		handler: eh,
+		logger:  logger,
+	}
+}
+
+type eventHandler struct {
+	path    string
+	handler event.EventHandler
+	logger  *zap.Logger
+}
+
+func (h *eventHandler) Mount(r *mux.Router) {
+	r.Handle(h.path, h).Methods("POST")
+}
+
+func (h *eventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ev, err := ReadEvent(r)
+	if err != nil {
+		h.logger.Error("failed decoding event", zap.Error(err))
+		w.WriteHeader(400)
+		return
+	}
+
+	if err := h.handler.HandleEvent(r.Context(), ev); err != nil {
+		h.logger.Error("failed handling event", zap.Error(err))
+		w.WriteHeader(500)
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
+// ReadEvent decodes an event from an HTTP request, detecting the encoding
+// from the request headers. Content types with no registered codec are
+// decoded with event.DefaultCodec.
+func ReadEvent(r *http.Request) (*event.Event, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var ev event.Event
+
+	if r.Header.Get(cloudEventsHeaderPrefix+"Specversion") != "" {
+		err = event.UnmarshalCloudEventBinary(cloudEventAttributes(r.Header), body, &ev)
+	} else {
+		// clients that predate codec support often send the native JSON
+		// encoding under a generic Content-Type such as text/plain, so
+		// anything unregistered falls back to the default codec
+		codec, lerr := event.LookupCodec(r.Header.Get("Content-Type"))
+		if lerr != nil {
+			codec = event.DefaultCodec
+		}
+		err = codec.Unmarshal(body, &ev)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+func cloudEventAttributes(hdr http.Header) map[string]string {
+	attrs := make(map[string]string)
+	for k := range hdr {
+		if strings.HasPrefix(k, cloudEventsHeaderPrefix) {
+			attrs[strings.ToLower(k[len(cloudEventsHeaderPrefix):])] = hdr.Get(k)
+		}
+	}
+	if ct := hdr.Get("Content-Type"); ct != "" {
+		attrs["datacontenttype"] = ct
+	}
+	return attrs
+}
+
+// NewEventRequest builds an HTTP POST request carrying ev in the given format.
+func NewEventRequest(ctx context.Context, url string, ev *event.Event, format EventFormat) (*http.Request, error) {
+	switch format {
+	case EventFormatCloudEventsStructured:
+		return NewEventRequestWithCodec(ctx, url, ev, &event.CloudEventsCodec{})
+	case EventFormatCloudEventsBinary:
+		attrs, body, err := event.MarshalCloudEventBinary(ev)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range attrs {
+			if k == "datacontenttype" {
+				req.Header.Set("Content-Type", v)
+				continue
+			}
+			req.Header.Set(cloudEventsHeaderPrefix+k, v)
+		}
+		return req, nil
+	default:
+		return NewEventRequestWithCodec(ctx, url, ev, &event.JSONCodec{})
+	}
+}
+
+// NewEventRequestWithCodec builds an HTTP POST request carrying ev encoded
+// by codec, with a Content-Type header naming the codec.
+func NewEventRequestWithCodec(ctx context.Context, url string, ev *event.Event, codec event.Codec) (*http.Request, error) {
+	body, err := codec.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", codec.ContentType())
+
+	return req, nil
+}
+
+// NewEventPublisher returns an event.EventHandler that POSTs every event
+// it receives to url, e.g. a downstream webhook.
+func NewEventPublisher(url string, format EventFormat) event.EventHandler {
+	return &eventPublisher{
+		client: http.DefaultClient,
+		url:    url,
+		newRequest: func(ctx context.Context, ev *event.Event) (*http.Request, error) {
+			return NewEventRequest(ctx, url, ev, format)
+		},
+	}
+}
+
+// NewEventPublisherWithCodec behaves like NewEventPublisher but encodes
+// events using an arbitrary event.Codec.
+func NewEventPublisherWithCodec(url string, codec event.Codec) event.EventHandler {
+	return &eventPublisher{
+		client: http.DefaultClient,
+		url:    url,
+		newRequest: func(ctx context.Context, ev *event.Event) (*http.Request, error) {
+			return NewEventRequestWithCodec(ctx, url, ev, codec)
+		},
+	}
+}
+
+type eventPublisher struct {
+	client     *http.Client
+	url        string
+	newRequest func(context.Context, *event.Event) (*http.Request, error)
+}
+
+func (p *eventPublisher) HandleEvent(ctx context.Context, ev *event.Event) error {
+	req, err := p.newRequest(ctx, ev)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection may be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event publish to %s failed with status %d", p.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *eventPublisher) Handles() []event.EventType {
+	return nil
+}
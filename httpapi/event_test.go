@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/sustglobal/gost/event"
+)
+
+type recordingHandler struct {
+	err    error
+	events []*event.Event
+}
+
+func (h *recordingHandler) HandleEvent(ctx context.Context, ev *event.Event) error {
+	h.events = append(h.events, ev)
+	return h.err
+}
+
+func (h *recordingHandler) Handles() []event.EventType {
+	return nil
+}
+
+func TestEventPublisherHandler(t *testing.T) {
+	formats := []EventFormat{
+		EventFormatJSON,
+		EventFormatCloudEventsStructured,
+		EventFormatCloudEventsBinary,
+	}
+
+	for _, format := range formats {
+		rh := &recordingHandler{}
+
+		rtr := mux.NewRouter()
+		NewEventHandler("/events", rh, zap.NewNop()).Mount(rtr)
+
+		srv := httptest.NewServer(rtr)
+		defer srv.Close()
+
+		want := &event.Event{
+			ID:     "abc",
+			Type:   event.EventType("example"),
+			Source: "/test",
+			Time:   time.Date(2022, time.March, 4, 1, 13, 44, 0, time.UTC),
+			Fields: []event.EventField{event.Field("foo", "bar")},
+		}
+
+		ep := NewEventPublisher(srv.URL+"/events", format)
+		if err := ep.HandleEvent(context.Background(), want); err != nil {
+			t.Fatalf("format %d: unexpected error: %v", format, err)
+		}
+
+		if len(rh.events) != 1 {
+			t.Fatalf("format %d: expected 1 event, got %d", format, len(rh.events))
+		}
+		if got := rh.events[0]; !reflect.DeepEqual(want, got) {
+			t.Errorf("format %d: unexpected event: want=%+v got=%+v", format, want, got)
+		}
+	}
+}
+
+func TestEventPublisherFailure(t *testing.T) {
+	rh := &recordingHandler{err: errors.New("failed")}
+
+	rtr := mux.NewRouter()
+	NewEventHandler("/events", rh, zap.NewNop()).Mount(rtr)
+
+	srv := httptest.NewServer(rtr)
+	defer srv.Close()
+
+	ep := NewEventPublisher(srv.URL+"/events", EventFormatJSON)
+	if err := ep.HandleEvent(context.Background(), event.NewEvent(event.EventType("example"))); err == nil {
+		t.Errorf("expected error from failing handler")
+	}
+}
+
+func TestEventPublisherWithCodec(t *testing.T) {
+	rh := &recordingHandler{}
+
+	rtr := mux.NewRouter()
+	NewEventHandler("/events", rh, zap.NewNop()).Mount(rtr)
+
+	srv := httptest.NewServer(rtr)
+	defer srv.Close()
+
+	codec := event.CompressedCodec(&event.CBORCodec{}, &event.GzipCompressor{})
+	want := event.NewEvent(event.EventType("example"), event.Field("foo", "bar"))
+
+	ep := NewEventPublisherWithCodec(srv.URL+"/events", codec)
+	if err := ep.HandleEvent(context.Background(), want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rh.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(rh.events))
+	}
+	if got := rh.events[0]; !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected event: want=%+v got=%+v", want, got)
+	}
+}
+
+func TestReadEventUnregisteredContentType(t *testing.T) {
+	want := event.NewEvent(event.EventType("example"), event.Field("foo", "bar"))
+	body, err := event.DefaultCodec.Marshal(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, ct := range []string{"text/plain", "application/x-www-form-urlencoded"} {
+		req := httptest.NewRequest("POST", "/events", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", ct)
+
+		got, err := ReadEvent(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", ct, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%s: unexpected event: want=%+v got=%+v", ct, want, got)
+		}
+	}
+}
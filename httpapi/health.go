@@ -1,22 +1,110 @@
 package httpapi
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/mux"
 )
 
-func NewHealthHandler() HandlerMounter {
-	return new(healthHandler)
+// HealthChecker is consulted by the health handler, which reports the
+// component unavailable if any check fails.
+type HealthChecker interface {
+	Name() string
+	CheckHealth(context.Context) error
 }
 
-type healthHandler struct{}
+// HealthRegistry holds the HealthCheckers of a health handler. Checkers
+// may be registered while the handler is serving.
+type HealthRegistry struct {
+	mu     sync.RWMutex
+	checks []healthCheck
+}
+
+type healthCheck struct {
+	HealthChecker
+
+	// advisory checks are reported without affecting the overall status
+	advisory bool
+}
+
+func (r *HealthRegistry) Register(c HealthChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, healthCheck{HealthChecker: c})
+}
+
+// RegisterAdvisory adds a check whose failures are reported in the health
+// response without making the component unavailable.
+func (r *HealthRegistry) RegisterAdvisory(c HealthChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, healthCheck{HealthChecker: c, advisory: true})
+}
+
+func (r *HealthRegistry) Checkers() []HealthChecker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checkers := make([]HealthChecker, 0, len(r.checks))
+	for _, c := range r.checks {
+		checkers = append(checkers, c.HealthChecker)
+	}
+	return checkers
+}
+
+func (r *HealthRegistry) snapshot() []healthCheck {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]healthCheck(nil), r.checks...)
+}
+
+func NewHealthHandler(checkers ...HealthChecker) HandlerMounter {
+	reg := new(HealthRegistry)
+	for _, c := range checkers {
+		reg.Register(c)
+	}
+	return NewHealthRegistryHandler(reg)
+}
+
+func NewHealthRegistryHandler(reg *HealthRegistry) HandlerMounter {
+	return &healthHandler{registry: reg}
+}
+
+type healthHandler struct {
+	registry *HealthRegistry
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
 
 func (h *healthHandler) Mount(r *mux.Router) {
 	r.Handle("/healthz", h)
 }
 
 func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{}`))
+	resp := healthResponse{Status: "ok"}
+	code := http.StatusOK
+
+	for _, c := range h.registry.snapshot() {
+		if resp.Checks == nil {
+			resp.Checks = make(map[string]string)
+		}
+		if err := c.CheckHealth(r.Context()); err != nil {
+			resp.Checks[c.Name()] = err.Error()
+			if !c.advisory {
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+			}
+		} else {
+			resp.Checks[c.Name()] = "ok"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
 }
@@ -0,0 +1,102 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type fixtureChecker struct {
+	name string
+	err  error
+}
+
+func (c *fixtureChecker) Name() string {
+	return c.name
+}
+
+func (c *fixtureChecker) CheckHealth(ctx context.Context) error {
+	return c.err
+}
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		checkers   []HealthChecker
+		wantStatus int
+		want       healthResponse
+	}{
+		{
+			wantStatus: 200,
+			want:       healthResponse{Status: "ok"},
+		},
+		{
+			checkers:   []HealthChecker{&fixtureChecker{name: "a"}},
+			wantStatus: 200,
+			want:       healthResponse{Status: "ok", Checks: map[string]string{"a": "ok"}},
+		},
+		{
+			checkers:   []HealthChecker{&fixtureChecker{name: "a"}, &fixtureChecker{name: "b", err: errors.New("down")}},
+			wantStatus: 503,
+			want:       healthResponse{Status: "unavailable", Checks: map[string]string{"a": "ok", "b": "down"}},
+		},
+	}
+
+	for _, tt := range tests {
+		rtr := mux.NewRouter()
+		NewHealthHandler(tt.checkers...).Mount(rtr)
+
+		req, err := http.NewRequest("GET", "/healthz", nil)
+		if err != nil {
+			t.Fatalf("Failed building HTTP request: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		rtr.ServeHTTP(rec, req)
+
+		res := rec.Result()
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("Received unexpected status code: want=%d got=%d", tt.wantStatus, res.StatusCode)
+		}
+
+		var got healthResponse
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatalf("failed decoding response: %v", err)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Errorf("unexpected response: want=%+v got=%+v", tt.want, got)
+		}
+	}
+}
+
+func TestHealthRegistry(t *testing.T) {
+	reg := new(HealthRegistry)
+	h := NewHealthRegistryHandler(reg)
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+		return rec.Code
+	}
+
+	if got := serve(); got != 200 {
+		t.Errorf("unexpected status code: want=200 got=%d", got)
+	}
+
+	reg.RegisterAdvisory(&fixtureChecker{name: "a", err: errors.New("degraded")})
+
+	if got := serve(); got != 200 {
+		t.Errorf("unexpected status code: want=200 got=%d", got)
+	}
+
+	reg.Register(&fixtureChecker{name: "b", err: errors.New("down")})
+
+	if got := serve(); got != 503 {
+		t.Errorf("unexpected status code: want=503 got=%d", got)
+	}
+}
@@ -0,0 +1,93 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"cloud.google.com/go/pubsub"
+
+	"github.com/sustglobal/gost/component"
+	"github.com/sustglobal/gost/event"
+)
+
+// NewPubSubDeadLetterSink publishes dead letters to a PubSub topic as JSON,
+// with the envelope of the failed event mirrored into message attributes
+// alongside the failing handler and attempt count. They may be replayed
+// with a PubSubDeadLetterAdapter.
+func NewPubSubDeadLetterSink(project, topic string) (*pubsubDeadLetterSink, error) {
+	pubsubClient, err := pubsub.NewClient(context.Background(), project)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pubsubDeadLetterSink{topic: pubsubClient.Topic(topic)}, nil
+}
+
+type pubsubDeadLetterSink struct {
+	topic *pubsub.Topic
+}
+
+func (s *pubsubDeadLetterSink) WriteDeadLetter(ctx context.Context, dl *event.DeadLetter) error {
+	msgData, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	res := s.topic.Publish(ctx, &pubsub.Message{
+		Data:       msgData,
+		Attributes: deadLetterAttributes(dl),
+	})
+
+	_, err = res.Get(ctx)
+	return err
+}
+
+const deadLetterContentType = "application/vnd.gost.dead-letter+json"
+
+func deadLetterAttributes(dl *event.DeadLetter) map[string]string {
+	attrs := eventAttributes(dl.Event)
+	// a distinct content type keeps a PubSubMessageEventAdapter from
+	// mistaking the dead letter for the event it carries
+	attrs[contentTypeAttribute] = deadLetterContentType
+	attrs["dead_letter_handler"] = dl.Handler
+	attrs["dead_letter_attempts"] = strconv.Itoa(dl.Attempts)
+	if dl.Route != 0 {
+		attrs["dead_letter_route"] = strconv.Itoa(dl.Route)
+	}
+	return attrs
+}
+
+// PubSubDeadLetterAdapter replays dead letters published by a PubSub dead
+// letter sink into EventHandler, typically the router whose handler failed
+// them, see event.ReplayDeadLetter. A dead letter that fails again is
+// reported to PubSub for redelivery rather than parked again.
+type PubSubDeadLetterAdapter struct {
+	event.EventHandler
+}
+
+func (eh *PubSubDeadLetterAdapter) HandleMessage(ctx context.Context, msg *pubsub.Message) error {
+	var dl event.DeadLetter
+	if err := json.Unmarshal(msg.Data, &dl); err != nil {
+		return fmt.Errorf("failed unmarshaling PubSub message as dead letter: %v", err)
+	}
+	if dl.Event == nil {
+		return fmt.Errorf("dead letter in PubSub message %s has no event", msg.ID)
+	}
+
+	return event.ReplayDeadLetter(ctx, eh.EventHandler, &dl)
+}
+
+// ListenForPubSubDeadLetters replays dead letters pushed to /dead-letter,
+// e.g. by a subscription to the dead letter topic, into the component's
+// inbound router.
+func ListenForPubSubDeadLetters(cmp *component.Component) {
+	mh := &PubSubMessageHandler{
+		Logger: cmp.Logger,
+		MessageHandler: &PubSubDeadLetterAdapter{
+			EventHandler: cmp.InboundEventRouter,
+		},
+	}
+	cmp.HTTPRouter.Handle("/dead-letter", mh).Methods("POST")
+}
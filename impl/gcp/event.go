@@ -2,7 +2,7 @@ package gcp
 
 import (
 	"context"
-	"encoding/json"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 
@@ -10,7 +10,15 @@ import (
 	"github.com/sustglobal/gost/event"
 )
 
+// contentTypeAttribute names the PubSub message attribute recording the
+// event.Codec used to encode the message payload
+const contentTypeAttribute = "content-type"
+
 func NewPubSubEventPublisher(project, topic string) (*pubsubEventPublisher, error) {
+	return NewPubSubEventPublisherWithCodec(project, topic, event.DefaultCodec)
+}
+
+func NewPubSubEventPublisherWithCodec(project, topic string, codec event.Codec) (*pubsubEventPublisher, error) {
 	pubsubClient, err := pubsub.NewClient(context.Background(), project)
 	if err != nil {
 		return nil, err
@@ -18,6 +26,7 @@ func NewPubSubEventPublisher(project, topic string) (*pubsubEventPublisher, erro
 
 	ep := pubsubEventPublisher{
 		topic: pubsubClient.Topic(topic),
+		codec: codec,
 	}
 
 	return &ep, nil
@@ -25,16 +34,21 @@ func NewPubSubEventPublisher(project, topic string) (*pubsubEventPublisher, erro
 
 type pubsubEventPublisher struct {
 	topic *pubsub.Topic
+	codec event.Codec
 }
 
 func (p *pubsubEventPublisher) HandleEvent(ctx context.Context, ev *event.Event) error {
-	msgData, err := json.Marshal(ev)
+	msgData, err := p.codec.Marshal(ev)
 	if err != nil {
 		return err
 	}
 
+	attrs := eventAttributes(ev)
+	attrs[contentTypeAttribute] = p.codec.ContentType()
+
 	msg := pubsub.Message{
-		Data: msgData,
+		Data:       msgData,
+		Attributes: attrs,
 	}
 
 	res := p.topic.Publish(ctx, &msg)
@@ -43,6 +57,30 @@ func (p *pubsubEventPublisher) HandleEvent(ctx context.Context, ev *event.Event)
 	return err
 }
 
+// eventAttributes mirrors the event envelope into PubSub message attributes
+// so that subscriptions can filter on it without decoding the payload.
+func eventAttributes(ev *event.Event) map[string]string {
+	attrs := map[string]string{
+		"event_type": string(ev.Type),
+	}
+
+	set := func(k, v string) {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	set("event_id", ev.ID)
+	set("event_source", ev.Source)
+	set("event_correlation_id", ev.CorrelationID)
+	set("event_causation_id", ev.CausationID)
+	set("event_schema_version", ev.SchemaVersion)
+	if !ev.Time.IsZero() {
+		attrs["event_time"] = ev.Time.Format(time.RFC3339Nano)
+	}
+
+	return attrs
+}
+
 func (p *pubsubEventPublisher) Handles() []event.EventType {
 	return nil
 }
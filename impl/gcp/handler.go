@@ -49,24 +49,83 @@ func (h *PubSubMessageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 
 type PubSubMessageEventAdapter struct {
 	event.EventHandler
+
+	// Lazy defers decoding field values until they are read, so large
+	// payloads are not held in memory twice. Handlers reading
+	// EventField.Value directly will then find a json.RawMessage, see
+	// event.UnmarshalLazy.
+	Lazy bool
+
+	// Logger defaults to a no-op logger
+	Logger *zap.Logger
 }
 
 func (eh *PubSubMessageEventAdapter) HandleMessage(ctx context.Context, msg *pubsub.Message) error {
+	// dead letters are replayed by PubSubDeadLetterAdapter; one arriving
+	// here is acknowledged, as it would only fail again on redelivery
+	if msg.Attributes[contentTypeAttribute] == deadLetterContentType {
+		logger := eh.Logger
+		if logger == nil {
+			logger = zap.NewNop()
+		}
+		logger.Warn("ignored dead letter received as event", zap.String("message_id", msg.ID))
+		return nil
+	}
+
+	// messages without a content type predate pluggable codecs and are
+	// decoded with the default (JSON) codec, as are those with a content
+	// type this process doesn't know: failing would only have PubSub
+	// redeliver them forever
+	codec, err := event.LookupCodec(msg.Attributes[contentTypeAttribute])
+	if err != nil {
+		codec = event.DefaultCodec
+	}
+
 	var ev event.Event
 
-	if err := json.Unmarshal(msg.Data, &ev); err != nil {
+	if eh.Lazy {
+		err = event.UnmarshalLazy(codec, msg.Data, &ev)
+	} else {
+		err = codec.Unmarshal(msg.Data, &ev)
+	}
+	if err != nil {
 		return fmt.Errorf("failed unmarshaling PubSub message as event: %v", err)
 	}
 
+	// events published before envelope metadata existed fall back to
+	// the identity and timing of the PubSub message that carried them
+	if ev.ID == "" {
+		ev.ID = msg.ID
+	}
+	if ev.Time.IsZero() {
+		ev.Time = msg.PublishTime
+	}
+
 	return eh.EventHandler.HandleEvent(ctx, &ev)
 }
 
-func ListenForPubSubMessages(cmp *component.Component) {
+type ListenOption func(*PubSubMessageEventAdapter)
+
+// WithLazyDecoding sets Lazy on the adapter created by
+// ListenForPubSubMessages.
+func WithLazyDecoding() ListenOption {
+	return func(a *PubSubMessageEventAdapter) {
+		a.Lazy = true
+	}
+}
+
+func ListenForPubSubMessages(cmp *component.Component, opts ...ListenOption) {
+	adapter := &PubSubMessageEventAdapter{
+		EventHandler: cmp.InboundEventRouter,
+		Logger:       cmp.Logger,
+	}
+	for _, opt := range opts {
+		opt(adapter)
+	}
+
 	mh := &PubSubMessageHandler{
-		Logger: cmp.Logger,
-		MessageHandler: &PubSubMessageEventAdapter{
-			EventHandler: cmp.InboundEventRouter,
-		},
+		Logger:         cmp.Logger,
+		MessageHandler: adapter,
 	}
 	cmp.HTTPRouter.Handle("/message", mh).Methods("POST")
 }